@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/spf13/cobra"
+)
+
+// stdinArg stands for stdin in place of a value or a file.
+const stdinArg = "-"
+
+// splitFilesAndKeys separates file arguments from key arguments. Everything
+// after the "--" separator is a key, without the separator only the last
+// argument is.
+func splitFilesAndKeys(cmd *cobra.Command, args []string) (files []string, keys []string) {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		return args[:dash], args[dash:]
+	}
+	return args[:len(args)-1], args[len(args)-1:]
+}
+
+// uniqueFiles removes repeated files, keeping the order of their first
+// appearance. Different paths leading to the same file are repeated files too.
+func uniqueFiles(files []string) []string {
+	seen := make(map[string]bool, len(files))
+	unique := make([]string, 0, len(files))
+	for _, file := range files {
+		canonical := lock.CanonicalPath(file)
+		if !seen[canonical] {
+			seen[canonical] = true
+			unique = append(unique, file)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// runChild runs the child process, forwarding termination signals to it, so
+// that kvf does not exit, and release its locks, while the child is running.
+// A failed child makes kvf exit with the same code, and a child killed by a
+// signal with 128 plus the signal number, as a shell does.
+func runChild(cmd *cobra.Command, child *exec.Cmd) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	err := child.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = child.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	code := exitErr.ExitCode()
+	if signalCode, ok := signalExitCode(exitErr); ok {
+		code = signalCode
+	}
+	if code <= 0 {
+		return err
+	}
+	// The child has reported its failure already.
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &childExitError{code: code}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "os/exec"
+
+// signalExitCode reports no signal, as a child is not killed by signals on
+// this platform.
+func signalExitCode(*exec.ExitError) (int, bool) {
+	return 0, false
+}
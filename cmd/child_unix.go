@@ -0,0 +1,18 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// signalExitCode returns the exit code of a shell for a child killed by a
+// signal, which is 128 plus the signal number.
+func signalExitCode(exitErr *exec.ExitError) (int, bool) {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return 128 + int(status.Signal()), true
+}
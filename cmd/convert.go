@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/oxio/kvf/internal/parser"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"strings"
+)
+
+func newConvertCmd() *cobra.Command {
+	var to *string
+	var inPlace *bool
+
+	cmd := &cobra.Command{
+		Use:   "convert <file> --to " + strings.Join(parser.Formats(), "|") + " [--in-place]",
+		Short: "Converts a file to another format",
+		Long: "Converts a file to another format and prints it, or with --in-place replaces the file with one having" +
+			" the extension of the format. Comments are kept where the format allows it. Anything which is lost, such" +
+			" as comments or keys the format can't hold, is reported as a warning on stderr.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return newUsageError("expected a single file")
+			}
+			if *to == "" {
+				return newUsageError("--%s is required", toFlag)
+			}
+			codec, err := parser.GetCodec(*to)
+			if err != nil {
+				return &usageError{err: err}
+			}
+
+			source := args[0]
+			if *inPlace {
+				target := strings.TrimSuffix(source, filepath.Ext(source)) + parser.ExtensionOf(*to)
+				warnings, err := kvf.ConvertFile(source, target, codec)
+				printWarnings(cmd, warnings)
+				return err
+			}
+
+			items, err := kvf.NewRepo(source, false).FindAll()
+			if err != nil {
+				return err
+			}
+
+			converted, warnings, err := kvf.Convert(*items, codec)
+			if err != nil {
+				return err
+			}
+			printWarnings(cmd, warnings)
+
+			_, err = codec.Encode(cmd.OutOrStdout(), converted)
+			return err
+		},
+	}
+
+	to = cmd.Flags().String(
+		toFlag,
+		"",
+		"The format to convert to: "+strings.Join(parser.Formats(), ", ")+".",
+	)
+	inPlace = cmd.Flags().Bool(
+		inPlaceFlag,
+		false,
+		"Replace the file with the converted one, having the extension of the format, e.g. app.properties becomes"+
+			" app.env. Fails if that file exists already.",
+	)
+
+	return cmd
+}
+
+func printWarnings(cmd *cobra.Command, warnings []string) {
+	for _, warning := range warnings {
+		cmd.PrintErrf("warning: %s\n", warning)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(newConvertCmd())
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name             string
+		fileName         string
+		content          string
+		to               string
+		expectedOutput   string
+		expectedWarnings string
+	}{
+		{
+			name:           "env to json",
+			fileName:       "app.env",
+			content:        "NAME=app\nPORT=80\nTEXT=\"a\nb\"\n",
+			to:             "json",
+			expectedOutput: "{\n  \"NAME\": \"app\",\n  \"PORT\": \"80\",\n  \"TEXT\": \"a\\nb\"\n}\n",
+		},
+		{
+			name:             "comments dropped in json",
+			fileName:         "app.env",
+			content:          "# comment\n\nNAME=app # inline\n",
+			to:               "json",
+			expectedOutput:   "{\n  \"NAME\": \"app\"\n}\n",
+			expectedWarnings: "warning: 1 comment(s) dropped\nwarning: inline comment of NAME dropped\n",
+		},
+		{
+			name:           "properties to env",
+			fileName:       "app.properties",
+			content:        "# comment\nurl : jdbc:x\\\n  y\nname = a b \n",
+			to:             "env",
+			expectedOutput: "# comment\nurl=jdbc:xy\nname='a b '\n",
+		},
+		{
+			name:           "env to properties",
+			fileName:       "app.env",
+			content:        "# comment\nKEY=\"a\nb\"\n",
+			to:             "properties",
+			expectedOutput: "# comment\nKEY=a\\nb\n",
+		},
+		{
+			name:           "env to ini",
+			fileName:       "app.env",
+			content:        "# comment\ntop=1\nserver.host=localhost\nserver.port=80\ndb.host=db\n",
+			to:             "ini",
+			expectedOutput: "; comment\ntop = 1\n\n[server]\nhost = localhost\nport = 80\n\n[db]\nhost = db\n",
+		},
+		{
+			name:             "ini can't hold multi-line values",
+			fileName:         "app.env",
+			content:          "A=1\nB=\"x\ny\"\n",
+			to:               "ini",
+			expectedOutput:   "A = 1\n",
+			expectedWarnings: "warning: unsupported value: INI values can't span several lines: B, the key is dropped\n",
+		},
+		{
+			name:             "duplicate keys",
+			fileName:         "app.env",
+			content:          "A=1\nA=2\n",
+			to:               "yaml",
+			expectedOutput:   "A: \"1\"\n",
+			expectedWarnings: "warning: duplicate key A is dropped, its first value is kept\n",
+		},
+		{
+			name:             "json key which isn't a variable name",
+			fileName:         "app.json",
+			content:          "{\"a=b\": \"c\", \"d\": \"e\"}",
+			to:               "env",
+			expectedOutput:   "d=e\n",
+			expectedWarnings: "warning: invalid key: a=b, the key is dropped\n",
+		},
+		{
+			name:             "keys which aren't variable names",
+			fileName:         "app.ini",
+			content:          "top = 1\n[server]\nhost = a\n",
+			to:               "env",
+			expectedOutput:   "top=1\n",
+			expectedWarnings: "warning: invalid key: server.host, the key is dropped\n",
+		},
+		{
+			name:             "escaped properties key",
+			fileName:         "app.properties",
+			content:          "my\\ key=v\n",
+			to:               "env",
+			expectedOutput:   "",
+			expectedWarnings: "warning: invalid key: my key, the key is dropped\n",
+		},
+		{
+			name:           "json to yaml keeps types",
+			fileName:       "app.json",
+			content:        "{\"name\": \"app\", \"port\": 80, \"debug\": false, \"opt\": null, \"ver\": \"1.0\"}",
+			to:             "yaml",
+			expectedOutput: "name: app\nport: 80\ndebug: false\nopt: null\nver: \"1.0\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tc.fileName)
+			assert.NoError(t, os.WriteFile(file, []byte(tc.content), 0644))
+
+			cmd, outBuff, errBuff := setUpTestConvertCmd()
+			cmd.SetArgs([]string{file, "--to", tc.to})
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, outBuff.String())
+			assert.Equal(t, tc.expectedWarnings, errBuff.String())
+			assertFileContentEquals(t, file, tc.content)
+		})
+	}
+}
+
+func TestConvert_InPlace(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.properties")
+	assert.NoError(t, os.WriteFile(source, []byte("# comment\nname : app\n"), 0644))
+
+	cmd, outBuff, _ := setUpTestConvertCmd()
+	cmd.SetArgs([]string{source, "--to", "env", "--in-place"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, outBuff.String())
+	assertFileContentEquals(t, filepath.Join(dir, "app.env"), "# comment\nname=app\n")
+	assert.NoFileExists(t, source)
+}
+
+func TestConvert_InPlaceSameFormat(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	assert.NoError(t, os.WriteFile(file, []byte("{\"b\":1,\"a\":\"x\"}"), 0644))
+
+	cmd, _, _ := setUpTestConvertCmd()
+	cmd.SetArgs([]string{file, "--to", "json", "--in-place"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assertFileContentEquals(t, file, "{\n  \"b\": 1,\n  \"a\": \"x\"\n}\n")
+}
+
+func TestConvert_InPlaceTargetExists(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.env")
+	target := filepath.Join(dir, "app.json")
+	assert.NoError(t, os.WriteFile(source, []byte("A=1\n"), 0644))
+	assert.NoError(t, os.WriteFile(target, []byte("{}"), 0644))
+
+	cmd, _, _ := setUpTestConvertCmd()
+	cmd.SilenceUsage = true
+	cmd.SetArgs([]string{source, "--to", "json", "--in-place"})
+	err := cmd.Execute()
+	assert.Equal(t, exitFailure, exitCode(err))
+	assertFileContentEquals(t, source, "A=1\n")
+	assertFileContentEquals(t, target, "{}")
+}
+
+func TestConvert_InPlaceLocksBothFiles(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.properties")
+	target := filepath.Join(dir, "app.env")
+	assert.NoError(t, os.WriteFile(source, []byte("name=app\n"), 0644))
+
+	for _, locked := range []string{source, target} {
+		l, err := lock.New(locked)
+		assert.NoError(t, err)
+		err = executeRootCmd(t, io.Discard, "convert", source, "--to", "env", "--in-place", "--no-wait")
+		assert.NoError(t, l.Release())
+		assert.Equal(t, exitLockTimeout, exitCode(err), locked)
+		assertFileContentEquals(t, source, "name=app\n")
+		assert.NoFileExists(t, target)
+	}
+}
+
+func TestConvert_InPlaceMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.properties")
+
+	cmd, _, _ := setUpTestConvertCmd()
+	cmd.SilenceUsage = true
+	cmd.SetArgs([]string{source, "--to", "env", "--in-place"})
+	err := cmd.Execute()
+	assert.Equal(t, exitFailure, exitCode(err))
+	assert.NoFileExists(t, filepath.Join(dir, "app.env"))
+}
+
+func TestConvert_Usage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.env")
+	assert.NoError(t, os.WriteFile(file, []byte("A=1\n"), 0644))
+
+	testCases := [][]string{
+		{file},
+		{file, "--to", "xml"},
+		{"--to", "json"},
+	}
+
+	for _, args := range testCases {
+		cmd, _, _ := setUpTestConvertCmd()
+		cmd.SilenceUsage = true
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		assert.Equal(t, exitUsage, exitCode(err), args)
+	}
+}
+
+func setUpTestConvertCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newConvertCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func newDeleteCmd() *cobra.Command {
+	var mustExist *bool
+	var skipMissingFiles *bool
+
+	cmd := &cobra.Command{
+		Use:     "delete <file1> [<file2> <file3> ...] <key> | <file1> [<file2> ...] -- <key1> [<key2> ...]",
+		Aliases: []string{"unset"},
+		Short:   "Deletes keys from the key-value file(s)",
+		Long: "Deletes keys from the key-value file(s). All files are updated in a single transaction: either every" +
+			" file is updated or none of them is.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return newUsageError("not enough arguments")
+			}
+
+			files, keys := splitFilesAndKeys(cmd, args)
+			if len(files) == 0 || len(keys) == 0 {
+				return newUsageError("not enough arguments")
+			}
+
+			repos := make([]*kvf.RepoImpl, 0, len(files))
+			for _, file := range uniqueFiles(files) {
+				_, err := os.Stat(file)
+				if err != nil {
+					if *skipMissingFiles && (os.IsNotExist(err) || os.IsPermission(err)) {
+						continue
+					}
+					return err
+				}
+				repos = append(repos, kvf.NewRepo(file, *skipMissingFiles))
+			}
+
+			return kvf.DeleteAll(repos, keys, *mustExist)
+		},
+	}
+
+	mustExist = cmd.Flags().BoolP(
+		mustExistFlag,
+		mustExistShortFlag,
+		false,
+		"Fail if any of the keys is not found in a file. None of the files is changed in that case.",
+	)
+	skipMissingFiles = cmd.Flags().BoolP(
+		skipMissingFilesFlag,
+		skipMissingFilesShortFlag,
+		false,
+		"Do not issue \"no such file or directory\" error on missing or inaccessible files.",
+	)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newDeleteCmd())
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDeleteKey_Success(t *testing.T) {
+	testCases := []struct {
+		name            string
+		originalContent string
+		args            []string
+		expectedContent string
+	}{
+		{
+			name:            "single key",
+			originalContent: "key1=value1\nkey2=value2\n",
+			args:            []string{"key1"},
+			expectedContent: "key2=value2\n",
+		},
+		{
+			name:            "every duplicate of the key",
+			originalContent: "key=value1\nother=foo\nkey=value2\n",
+			args:            []string{"key"},
+			expectedContent: "other=foo\n",
+		},
+		{
+			name:            "comments and empty lines are preserved",
+			originalContent: "# comment\n\nkey1=value1\n\n# another comment\nkey2=value2\n",
+			args:            []string{"key1"},
+			expectedContent: "# comment\n\n\n# another comment\nkey2=value2\n",
+		},
+		{
+			name:            "multiple keys",
+			originalContent: "key1=value1\nkey2=value2\nkey3=value3\n",
+			args:            []string{"--", "key1", "key3"},
+			expectedContent: "key2=value2\n",
+		},
+		{
+			name:            "missing key is ignored",
+			originalContent: "key1=value1\n",
+			args:            []string{"bogus-key"},
+			expectedContent: "key1=value1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testFile, err := createRandomTestFileWithContent(tc.originalContent)
+			assert.NoError(t, err)
+			defer removeTestFile(testFile.Name())
+
+			cmd, _, _ := setUpTestDeleteCmd()
+			cmd.SetArgs(append([]string{testFile.Name()}, tc.args...))
+
+			err = cmd.Execute()
+			assert.NoError(t, err)
+
+			assertFileContentEquals(t, testFile.Name(), tc.expectedContent)
+		})
+	}
+}
+
+func TestDeleteKey_MultipleFiles(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=value1\nfoo=bar\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("key=value2\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, _ := setUpTestDeleteCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "key"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	assertFileContentEquals(t, testFile1.Name(), "foo=bar\n")
+	assertFileContentEquals(t, testFile2.Name(), "")
+}
+
+func TestDeleteKey_MustExist(t *testing.T) {
+	originalContent := "key1=value1\nkey2=value2\n"
+	testFile, err := createRandomTestFileWithContent(originalContent)
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	cmd, _, errBuff := setUpTestDeleteCmd()
+	cmd.SetArgs([]string{testFile.Name(), "--must-exist", "--", "key1", "bogus-key"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+
+	errContent, err := io.ReadAll(errBuff)
+	assert.NoError(t, err)
+	assert.Contains(t, string(errContent), "item not found: bogus-key")
+
+	assertFileContentEquals(t, testFile.Name(), originalContent)
+}
+
+func TestDeleteKey_MustExistInAllFiles(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=value1\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("other=value2\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, errBuff := setUpTestDeleteCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "key", "-e"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, errBuff.String(), "item not found: key")
+
+	assertFileContentEquals(t, testFile1.Name(), "key=value1\n")
+	assertFileContentEquals(t, testFile2.Name(), "other=value2\n")
+}
+
+func TestDeleteKey_UnchangedFileIsNotReplaced(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	before, err := os.Stat(testFile.Name())
+	assert.NoError(t, err)
+
+	cmd, _, _ := setUpTestDeleteCmd()
+	cmd.SetArgs([]string{testFile.Name(), "bogus-key"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	after, err := os.Stat(testFile.Name())
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "the file should keep its inode")
+	assert.Equal(t, before.ModTime(), after.ModTime())
+	assertFileContentEquals(t, testFile.Name(), "key=value\n")
+}
+
+func TestDeleteKey_MissingFile(t *testing.T) {
+	filePath := getRandomTestFilePath()
+
+	cmd, _, errBuff := setUpTestDeleteCmd()
+	cmd.SetArgs([]string{filePath, "key"})
+
+	err := cmd.Execute()
+	assert.Error(t, err)
+
+	errContent, err := io.ReadAll(errBuff)
+	assert.NoError(t, err)
+	assert.Contains(t, string(errContent), "no such file or directory")
+
+	cmd, _, _ = setUpTestDeleteCmd()
+	cmd.SetArgs([]string{filePath, "key", "-m"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func setUpTestDeleteCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newDeleteCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
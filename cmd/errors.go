@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/oxio/kvf/internal/parser"
+)
+
+const (
+	exitOK          = 0
+	exitNotFound    = 1
+	exitUsage       = 2
+	exitFailure     = 3
+	exitLockTimeout = 4
+	exitCondition   = 5
+)
+
+type usageError struct {
+	err error
+}
+
+func newUsageError(format string, a ...any) error {
+	return &usageError{err: fmt.Errorf(format, a...)}
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+type keyNotFoundError struct {
+	key string
+}
+
+func newKeyNotFoundError(key string) error {
+	return &keyNotFoundError{key: key}
+}
+
+func (e *keyNotFoundError) Error() string {
+	return "key not found: " + e.key
+}
+
+func (e *keyNotFoundError) Unwrap() error {
+	return kvf.ErrItemNotFound
+}
+
+// childExitError passes the exit code of a child process through.
+type childExitError struct {
+	code int
+}
+
+func (e *childExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.code)
+}
+
+// exitCode maps an error returned by a command to the process exit code.
+// Errors that are not recognized are treated as I/O or parse failures.
+func exitCode(err error) int {
+	var usageErr *usageError
+	var childErr *childExitError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &childErr):
+		return childErr.code
+	case errors.As(err, &usageErr), errors.Is(err, parser.ErrEmptyKey):
+		return exitUsage
+	case errors.Is(err, lock.ErrTimeout):
+		return exitLockTimeout
+	case errors.Is(err, kvf.ErrConditionFailed):
+		return exitCondition
+	case errors.Is(err, kvf.ErrItemNotFound):
+		return exitNotFound
+	default:
+		return exitFailure
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func newExecCmd() *cobra.Command {
+	var clean *bool
+	var override *bool
+	var noOverride *bool
+	var includes *[]string
+	var excludes *[]string
+	var skipMissingFiles *bool
+	var expand *bool
+	var expandEnv *bool
+
+	cmd := &cobra.Command{
+		Use:   "exec <file1> [<file2> <file3> ...] -- <command> [<args> ...]",
+		Short: "Runs a command with the keys of the file(s) as environment variables",
+		Long: "Runs a command with the keys of the file(s) as environment variables. The keys are resolved the same" +
+			" way get does: the last file containing a key wins. The exit code of the command is passed through.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 1 || dash == len(args) {
+				return newUsageError("expected <file>... -- <command>")
+			}
+			files, command := args[:dash], args[dash:]
+
+			include, err := newKeyFilter(*includes, nil)
+			if err != nil {
+				return err
+			}
+			exclude, err := newKeyFilter(*excludes, nil)
+			if err != nil {
+				return err
+			}
+
+			items, err := kvf.ResolveAll(files, *skipMissingFiles)
+			if err != nil {
+				return err
+			}
+
+			var expander *kvf.Expander
+			if *expand || *expandEnv {
+				expander = kvf.NewExpander(items, *expandEnv)
+			}
+
+			var env []string
+			if !*clean {
+				env = os.Environ()
+			}
+			positions := make(map[string]int, len(env))
+			for i, variable := range env {
+				name, _, _ := strings.Cut(variable, "=")
+				positions[name] = i
+			}
+
+			for _, item := range items {
+				if !include.Match(item.Key) || (len(*excludes) > 0 && exclude.Match(item.Key)) {
+					continue
+				}
+
+				val := item.Val
+				if expander != nil {
+					val, err = expander.Expand(item.Key)
+					if err != nil {
+						return err
+					}
+				}
+
+				variable := item.Key + "=" + val
+				if pos, ok := positions[item.Key]; ok {
+					if *override && !*noOverride {
+						env[pos] = variable
+					}
+					continue
+				}
+				positions[item.Key] = len(env)
+				env = append(env, variable)
+			}
+
+			child := exec.Command(command[0], command[1:]...)
+			// An empty environment has to be non-nil, a nil one is inherited.
+			child.Env = append([]string{}, env...)
+			child.Stdin = cmd.InOrStdin()
+			child.Stdout = cmd.OutOrStdout()
+			child.Stderr = cmd.ErrOrStderr()
+
+			return runChild(cmd, child)
+		},
+	}
+
+	clean = cmd.Flags().Bool(
+		cleanFlag,
+		false,
+		"Run the command with the keys of the file(s) only, instead of adding them to the current environment.",
+	)
+	override = cmd.Flags().Bool(
+		overrideFlag,
+		true,
+		"Let the keys of the file(s) override variables which are already set in the environment. This is the"+
+			" default.",
+	)
+	noOverride = cmd.Flags().Bool(
+		noOverrideFlag,
+		false,
+		"Keep variables which are already set in the environment instead of overriding them.",
+	)
+	includes = cmd.Flags().StringArrayP(
+		includeFlag,
+		includeShortFlag,
+		nil,
+		"Only pass keys matching the glob pattern. Can be repeated.",
+	)
+	excludes = cmd.Flags().StringArray(
+		excludeFlag,
+		nil,
+		"Do not pass keys matching the glob pattern. Can be repeated.",
+	)
+	skipMissingFiles = cmd.Flags().BoolP(
+		skipMissingFilesFlag,
+		skipMissingFilesShortFlag,
+		false,
+		"Do not issue \"no such file or directory\" error on missing or inaccessible files.",
+	)
+	expand = cmd.Flags().Bool(
+		expandFlag,
+		false,
+		"Resolve ${VAR}, $VAR, ${VAR:-default} and ${VAR:?message} references in the values.",
+	)
+	expandEnv = cmd.Flags().Bool(
+		expandEnvFlag,
+		false,
+		"Like --expand, but also resolve references to variables of the process environment which are not found"+
+			" in the files.",
+	)
+	cmd.MarkFlagsMutuallyExclusive(overrideFlag, noOverrideFlag)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newExecCmd())
+}
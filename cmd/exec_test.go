@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("APP_ENV=prod\nAPP_PORT=80\nDB_HOST=db\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("APP_ENV=dev\nURL=\"http://${DB_HOST}\"\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	script := `printf '%s|%s|%s|%s|%s' "$APP_ENV" "$APP_PORT" "$DB_HOST" "$URL" "$KVF_TEST_PRESET"`
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "last file wins",
+			args:           []string{},
+			expectedOutput: "dev|80|db|http://${DB_HOST}|preset",
+		},
+		{
+			name:           "expand",
+			args:           []string{"--expand"},
+			expectedOutput: "dev|80|db|http://db|preset",
+		},
+		{
+			name:           "override by default",
+			args:           []string{"--override"},
+			expectedOutput: "dev|80|db|http://${DB_HOST}|preset",
+		},
+		{
+			name:           "no override",
+			args:           []string{"--no-override"},
+			expectedOutput: "env|80|db|http://${DB_HOST}|preset",
+		},
+		{
+			name:           "include",
+			args:           []string{"--include", "APP_*", "-i", "URL"},
+			expectedOutput: "dev|80||http://${DB_HOST}|preset",
+		},
+		{
+			name:           "exclude",
+			args:           []string{"--include", "APP_*", "--exclude", "*_PORT"},
+			expectedOutput: "dev||||preset",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "env")
+			t.Setenv("KVF_TEST_PRESET", "preset")
+
+			cmd, outBuff, _ := setUpTestExecCmd()
+			args := append([]string{testFile1.Name(), testFile2.Name()}, tc.args...)
+			cmd.SetArgs(append(args, "--", "sh", "-c", script))
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, outBuff.String())
+		})
+	}
+}
+
+func TestExec_Clean(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("KEY=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+	t.Setenv("KVF_TEST_PRESET", "preset")
+
+	shell, err := exec.LookPath("sh")
+	assert.NoError(t, err)
+
+	cmd, outBuff, _ := setUpTestExecCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--clean", "--", shell, "-c", `printf '%s|%s' "$KEY" "$KVF_TEST_PRESET"`})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "value|", outBuff.String())
+}
+
+func TestExec_ExitCode(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("CODE=9\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, outBuff, errBuff := setUpTestExecCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--", "sh", "-c", `exit "$CODE"`})
+	err = cmd.Execute()
+	assert.Equal(t, 9, exitCode(err))
+	// The failure is reported by the command itself, without the usage.
+	assert.Empty(t, outBuff.String())
+	assert.Empty(t, errBuff.String())
+}
+
+func TestExec_KilledBySignal(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, outBuff, errBuff := setUpTestExecCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--", "sh", "-c", "kill -TERM $$"})
+	err = cmd.Execute()
+	assert.Equal(t, 128+int(syscall.SIGTERM), exitCode(err))
+	assert.Empty(t, outBuff.String())
+	assert.Empty(t, errBuff.String())
+}
+
+func setUpTestExecCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newExecCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
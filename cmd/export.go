@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/dialect"
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func newExportCmd() *cobra.Command {
+	var format *string
+	var includes *[]string
+	var excludes *[]string
+	var skipMissingFiles *bool
+	var expand *bool
+	var expandEnv *bool
+
+	cmd := &cobra.Command{
+		Use:   "export <file1> [<file2> <file3> ...] [--format|-f " + strings.Join(dialect.Names(), "|") + "]",
+		Short: "Prints the keys of the file(s) in the syntax of another tool",
+		Long: "Prints the keys of the file(s) in the syntax of another tool, escaped as the tool requires. The keys" +
+			" are resolved the same way get does: the last file containing a key wins.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return newUsageError("not enough arguments")
+			}
+
+			d, err := dialect.Get(*format)
+			if err != nil {
+				return &usageError{err: err}
+			}
+
+			include, err := newKeyFilter(*includes, nil)
+			if err != nil {
+				return err
+			}
+			exclude, err := newKeyFilter(*excludes, nil)
+			if err != nil {
+				return err
+			}
+
+			items, err := kvf.ResolveAll(args, *skipMissingFiles)
+			if err != nil {
+				return err
+			}
+
+			var expander *kvf.Expander
+			if *expand || *expandEnv {
+				expander = kvf.NewExpander(items, *expandEnv)
+			}
+
+			// Everything is rendered before printing, so that an invalid key
+			// doesn't leave the output half-written.
+			var out strings.Builder
+			var errs []error
+			for _, item := range items {
+				if !include.Match(item.Key) || (len(*excludes) > 0 && exclude.Match(item.Key)) {
+					continue
+				}
+
+				val := item.Val
+				if expander != nil {
+					val, err = expander.Expand(item.Key)
+					if err != nil {
+						return err
+					}
+				}
+
+				rendered, err := d.Render(item.Key, val)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				out.WriteString(rendered)
+			}
+			if len(errs) > 0 {
+				return errors.Join(errs...)
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), out.String())
+			return nil
+		},
+	}
+
+	format = cmd.Flags().StringP(
+		formatFlag,
+		formatShortFlag,
+		"sh",
+		"The syntax to print the keys in, one of: "+strings.Join(dialect.Names(), ", ")+".",
+	)
+	includes = cmd.Flags().StringArrayP(
+		includeFlag,
+		includeShortFlag,
+		nil,
+		"Only print keys matching the glob pattern. Can be repeated.",
+	)
+	excludes = cmd.Flags().StringArray(
+		excludeFlag,
+		nil,
+		"Do not print keys matching the glob pattern. Can be repeated.",
+	)
+	skipMissingFiles = cmd.Flags().BoolP(
+		skipMissingFilesFlag,
+		skipMissingFilesShortFlag,
+		false,
+		"Do not issue \"no such file or directory\" error on missing or inaccessible files.",
+	)
+	expand = cmd.Flags().Bool(
+		expandFlag,
+		false,
+		"Resolve ${VAR}, $VAR, ${VAR:-default} and ${VAR:?message} references in the values.",
+	)
+	expandEnv = cmd.Flags().Bool(
+		expandEnvFlag,
+		false,
+		"Like --expand, but also resolve references to variables of the process environment which are not found"+
+			" in the files.",
+	)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newExportCmd())
+}
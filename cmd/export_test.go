@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	content := "A=simple\nB=\"it's $x \\\"q\\\" \\\\ `b`\"\nC='  lead # hash\\'\n"
+
+	testCases := []struct {
+		format         string
+		expectedOutput string
+	}{
+		{
+			format:         "sh",
+			expectedOutput: "export A='simple'\nexport B='it'\\''s $x \"q\" \\ `b`'\nexport C='  lead # hash\\'\n",
+		},
+		{
+			format:         "fish",
+			expectedOutput: "set -gx A 'simple'\nset -gx B 'it\\'s $x \"q\" \\\\ `b`'\nset -gx C '  lead # hash\\\\'\n",
+		},
+		{
+			format:         "powershell",
+			expectedOutput: "$env:A = 'simple'\n$env:B = 'it''s $x \"q\" \\ `b`'\n$env:C = '  lead # hash\\'\n",
+		},
+		{
+			format:         "docker-env",
+			expectedOutput: "A=simple\nB=it's $x \"q\" \\ `b`\nC=  lead # hash\\\n",
+		},
+		{
+			format:         "systemd",
+			expectedOutput: "A=\"simple\"\nB=\"it's \\$x \\\"q\\\" \\\\ \\`b\\`\"\nC=\"  lead # hash\\\\\"\n",
+		},
+		{
+			format:         "github-env",
+			expectedOutput: "A=simple\nB=it's $x \"q\" \\ `b`\nC=  lead # hash\\\n",
+		},
+		{
+			format:         "make",
+			expectedOutput: "A := simple\nB := it's $$x \"q\" \\ `b`\ndefine C\n  lead # hash\\$()\nendef\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(content)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, _ := setUpTestExportCmd()
+			cmd.SetArgs([]string{tmpFile.Name(), "--format", tc.format})
+			err = cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, outBuff.String())
+		})
+	}
+}
+
+func TestExport_MultiLineValues(t *testing.T) {
+	content := "KEY=\"line 1\nline 2\"\n"
+
+	testCases := []struct {
+		format         string
+		expectedOutput string
+		expectedCode   int
+	}{
+		{format: "sh", expectedOutput: "^export KEY='line 1\nline 2'\n$"},
+		{format: "systemd", expectedOutput: "^KEY=\"line 1\nline 2\"\n$"},
+		{format: "make", expectedOutput: "^define KEY\nline 1\nline 2\nendef\n$"},
+		{format: "github-env", expectedOutput: "^KEY<<(ghadelimiter_[0-9a-f]{32})\nline 1\nline 2\n(ghadelimiter_[0-9a-f]{32})\n$"},
+		{format: "docker-env", expectedCode: exitFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(content)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, _ := setUpTestExportCmd()
+			cmd.SetArgs([]string{tmpFile.Name(), "-f", tc.format})
+			err = cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			if tc.expectedCode == exitOK {
+				assert.Regexp(t, tc.expectedOutput, outBuff.String())
+			}
+		})
+	}
+}
+
+func TestExport_Errors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		content      string
+		args         []string
+		expectedErr  string
+		expectedCode int
+	}{
+		{
+			name:         "unknown format",
+			content:      "KEY=value\n",
+			args:         []string{"--format", "xml"},
+			expectedErr:  "unknown dialect \"xml\"",
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "invalid names",
+			content:      "my-key=value\nOK=value\nother.key=value\n",
+			args:         []string{"--format", "sh"},
+			expectedErr:  "key is not a valid variable name: my-key\nkey is not a valid variable name: other.key",
+			expectedCode: exitFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(tc.content)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, errBuff := setUpTestExportCmd()
+			cmd.SilenceUsage = true
+			cmd.SetArgs(append([]string{tmpFile.Name()}, tc.args...))
+			err = cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			assert.Contains(t, errBuff.String(), tc.expectedErr)
+			assert.Empty(t, outBuff.String())
+		})
+	}
+}
+
+func TestExport_Filters(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("APP_ENV=dev\nAPP_PORT=80\nDB_HOST=db\nURL=http://${DB_HOST}\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, outBuff, _ := setUpTestExportCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "-f", "docker-env", "--expand", "-i", "APP_*", "-i", "URL", "--exclude", "*_PORT"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "APP_ENV=dev\nURL=http://db\n", outBuff.String())
+}
+
+func setUpTestExportCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newExportCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
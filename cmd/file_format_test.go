@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileFormat_Get(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		content  string
+		key      string
+		expected string
+	}{
+		{
+			name:     "properties with colon",
+			fileName: "app.properties",
+			content:  "# comment\n! comment\nname : app\n",
+			key:      "name",
+			expected: "app",
+		},
+		{
+			name:     "properties with whitespace separator",
+			fileName: "app.properties",
+			content:  "name   app\n",
+			key:      "name",
+			expected: "app",
+		},
+		{
+			name:     "properties continuation",
+			fileName: "app.properties",
+			content:  "url = jdbc:postgresql://\\\n    localhost/app\nother=1\n",
+			key:      "url",
+			expected: "jdbc:postgresql://localhost/app",
+		},
+		{
+			name:     "properties continuation at the end of the file",
+			fileName: "app.properties",
+			content:  "url = jdbc\\\n",
+			key:      "url",
+			expected: "jdbc",
+		},
+		{
+			name:     "properties key continued at the end of the file",
+			fileName: "app.properties",
+			content:  "k\\",
+			key:      "k",
+			expected: "",
+		},
+		{
+			name:     "properties escapes",
+			fileName: "app.properties",
+			content:  "a\\ key=caf\\u00e9\\tx \\ud83d\\ude00\\\\\n",
+			key:      "a key",
+			expected: "café\tx 😀\\",
+		},
+		{
+			name:     "ini section",
+			fileName: "app.ini",
+			content:  "top = 1\n[server]\nhost = localhost ; comment\n[db]\nhost = db\n",
+			key:      "server.host",
+			expected: "localhost",
+		},
+		{
+			name:     "ini key before sections",
+			fileName: "app.ini",
+			content:  "top = 1\n[server]\nhost = localhost\n",
+			key:      "top",
+			expected: "1",
+		},
+		{
+			name:     "ini quoted",
+			fileName: "app.ini",
+			content:  "[db]\nurl = \" a ; b \" ; comment\n",
+			key:      "db.url",
+			expected: " a ; b ",
+		},
+		{
+			name:     "json string",
+			fileName: "app.json",
+			content:  "{\n  \"name\": \"a\\nb\",\n  \"port\": 8080\n}\n",
+			key:      "name",
+			expected: "a\nb",
+		},
+		{
+			name:     "json number",
+			fileName: "app.json",
+			content:  "{\"name\": \"app\", \"port\": 8080}",
+			key:      "port",
+			expected: "8080",
+		},
+		{
+			name:     "json null",
+			fileName: "app.json",
+			content:  "{\"opt\": null}",
+			key:      "opt",
+			expected: "",
+		},
+		{
+			name:     "yaml",
+			fileName: "app.yaml",
+			content:  "# comment\nname: app # inline\nport: 8080\n",
+			key:      "port",
+			expected: "8080",
+		},
+		{
+			name:     "yml",
+			fileName: "app.yml",
+			content:  "text: |\n  a\n  b\n",
+			key:      "text",
+			expected: "a\nb\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tc.fileName)
+			assert.NoError(t, os.WriteFile(file, []byte(tc.content), 0644))
+
+			cmd, outBuff, _ := setUpTestGetCmd()
+			cmd.SetArgs([]string{file, tc.key})
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, outBuff.String())
+		})
+	}
+}
+
+func TestFileFormat_Set(t *testing.T) {
+	testCases := []struct {
+		name            string
+		fileName        string
+		content         string
+		args            []string
+		expectedContent string
+	}{
+		{
+			name:            "properties keeps formatting",
+			fileName:        "app.properties",
+			content:         "# comment\nname : app\nurl = a\\\n  b\n",
+			args:            []string{"--", "name=new", "url=x y"},
+			expectedContent: "# comment\nname : new\nurl = x y\n",
+		},
+		{
+			name:            "properties new key",
+			fileName:        "app.properties",
+			content:         "name=app\n",
+			args:            []string{"--", "a key=:line\none"},
+			expectedContent: "name=app\na\\ key=\\:line\\none\n",
+		},
+		{
+			name:            "ini existing key",
+			fileName:        "app.ini",
+			content:         "[server]\nhost = localhost ; comment\n",
+			args:            []string{"server.host", "a ; b"},
+			expectedContent: "[server]\nhost = \"a ; b\" ; comment\n",
+		},
+		{
+			name:            "ini new key of section",
+			fileName:        "app.ini",
+			content:         "top = 1\n\n[server]\nhost = localhost\n\n[db]\nhost = db\n",
+			args:            []string{"--", "server.port=80", "top2=2"},
+			expectedContent: "top = 1\ntop2 = 2\n\n[server]\nhost = localhost\nport = 80\n\n[db]\nhost = db\n",
+		},
+		{
+			name:            "ini new section",
+			fileName:        "app.ini",
+			content:         "[server]\nhost = localhost\n",
+			args:            []string{"cache.redis.ttl", "5"},
+			expectedContent: "[server]\nhost = localhost\n\n[cache.redis]\nttl = 5\n",
+		},
+		{
+			name:            "json keeps types",
+			fileName:        "app.json",
+			content:         "{\"name\": \"app\", \"port\": 80, \"debug\": false}",
+			args:            []string{"--", "port=8080", "debug=yes", "url=<a&b>"},
+			expectedContent: "{\n  \"name\": \"app\",\n  \"port\": 8080,\n  \"debug\": \"yes\",\n  \"url\": \"<a&b>\"\n}\n",
+		},
+		{
+			name:            "json new file",
+			fileName:        "app.json",
+			args:            []string{"name", "app"},
+			expectedContent: "{\n  \"name\": \"app\"\n}\n",
+		},
+		{
+			name:            "yaml keeps comments",
+			fileName:        "app.yaml",
+			content:         "# about name\nname: app # inline\nport: 80\n",
+			args:            []string{"--", "name=true", "port=81", "new=x"},
+			expectedContent: "# about name\nname: \"true\" # inline\nport: 81\nnew: x\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tc.fileName)
+			if tc.content != "" {
+				assert.NoError(t, os.WriteFile(file, []byte(tc.content), 0644))
+			}
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SetArgs(append([]string{file}, tc.args...))
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assertFileContentEquals(t, file, tc.expectedContent)
+		})
+	}
+}
+
+func TestFileFormat_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		content  string
+		args     []string
+	}{
+		{
+			name:     "nested json",
+			fileName: "app.json",
+			content:  "{\"db\": {\"host\": \"localhost\"}}",
+			args:     []string{"db", "x"},
+		},
+		{
+			name:     "json array",
+			fileName: "app.json",
+			content:  "[1]",
+			args:     []string{"db", "x"},
+		},
+		{
+			name:     "nested yaml",
+			fileName: "app.yaml",
+			content:  "db:\n  host: localhost\n",
+			args:     []string{"db", "x"},
+		},
+		{
+			name:     "several yaml documents",
+			fileName: "app.yaml",
+			content:  "a: 1\n---\nb: 2\n",
+			args:     []string{"a", "x"},
+		},
+		{
+			name:     "duplicate json key",
+			fileName: "app.json",
+			content:  "{\"a\": \"1\", \"a\": \"2\"}",
+			args:     []string{"a", "x"},
+		},
+		{
+			name:     "multi-line ini value",
+			fileName: "app.ini",
+			content:  "[db]\nhost = localhost\n",
+			args:     []string{"db.host", "a\nb"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tc.fileName)
+			assert.NoError(t, os.WriteFile(file, []byte(tc.content), 0644))
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SilenceUsage = true
+			cmd.SetArgs(append([]string{file}, tc.args...))
+			err := cmd.Execute()
+			assert.Equal(t, exitFailure, exitCode(err))
+			assertFileContentEquals(t, file, tc.content)
+		})
+	}
+}
+
+func TestFileFormat_Flag(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.conf")
+	assert.NoError(t, os.WriteFile(file, []byte("[server]\nhost = localhost\n"), 0644))
+
+	err := executeRootCmd(t, io.Discard, "set", file, "server.port", "80", "--file-format", "ini")
+	assert.NoError(t, err)
+	assertFileContentEquals(t, file, "[server]\nhost = localhost\nport = 80\n")
+
+	err = executeRootCmd(t, io.Discard, "get", file, "server.host", "--file-format", "xml")
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestFileFormat_MixedFiles(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "defaults.env")
+	jsonFile := filepath.Join(dir, "local.json")
+	assert.NoError(t, os.WriteFile(envFile, []byte("host=localhost\nport=80\n"), 0644))
+	assert.NoError(t, os.WriteFile(jsonFile, []byte("{\"port\": 8080}"), 0644))
+
+	cmd, outBuff, _ := setUpTestListCmd()
+	cmd.SetArgs([]string{envFile, jsonFile, "--with-values"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "host=localhost\nport=8080\n", outBuff.String())
+}
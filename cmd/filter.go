@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"path"
+	"regexp"
+)
+
+type keyFilter struct {
+	globs   []string
+	regexps []*regexp.Regexp
+}
+
+func newKeyFilter(globs []string, patterns []string) (*keyFilter, error) {
+	filter := &keyFilter{}
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, newUsageError("invalid glob %q: %w", glob, err)
+		}
+		filter.globs = append(filter.globs, glob)
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, newUsageError("invalid regex %q: %w", pattern, err)
+		}
+		filter.regexps = append(filter.regexps, re)
+	}
+	return filter, nil
+}
+
+// Match reports whether the key matches any of the globs or regular
+// expressions. A filter without any patterns matches every key.
+func (f *keyFilter) Match(key string) bool {
+	if len(f.globs) == 0 && len(f.regexps) == 0 {
+		return true
+	}
+	for _, glob := range f.globs {
+		if ok, _ := path.Match(glob, key); ok {
+			return true
+		}
+	}
+	for _, re := range f.regexps {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
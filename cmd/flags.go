@@ -5,4 +5,55 @@ const (
 	defaultValShortFlag       = "d"
 	skipMissingFilesFlag      = "skip-missing-files"
 	skipMissingFilesShortFlag = "m"
+	mustExistFlag             = "must-exist"
+	mustExistShortFlag        = "e"
+	keysOnlyFlag              = "keys-only"
+	keysOnlyShortFlag         = "k"
+	withValuesFlag            = "with-values"
+	withValuesShortFlag       = "v"
+	globFlag                  = "glob"
+	globShortFlag             = "g"
+	regexFlag                 = "regex"
+	regexShortFlag            = "r"
+	sortFlag                  = "sort"
+	sortShortFlag             = "s"
+	exportFlag                = "export"
+	exportShortFlag           = "x"
+	expandFlag                = "expand"
+	expandEnvFlag             = "expand-env"
+	lockDirFlag               = "lock-dir"
+	lockSidecarFlag           = "lock-sidecar"
+	lockTimeoutFlag           = "lock-timeout"
+	noWaitFlag                = "no-wait"
+	fileFormatFlag            = "file-format"
+	ifAbsentFlag              = "if-absent"
+	ifPresentFlag             = "if-present"
+	ifValueFlag               = "if-value"
+	ifVersionFlag             = "if-version"
+	printVersionFlag          = "print-version"
+	byFlag                    = "by"
+	initFlag                  = "init"
+	minFlag                   = "min"
+	maxFlag                   = "max"
+	wrapFlag                  = "wrap"
+	valueFileFlag             = "value-file"
+	keepNewlineFlag           = "keep-newline"
+	batchFlag                 = "batch"
+	batchShortFlag            = "b"
+	formatFlag                = "format"
+	formatShortFlag           = "f"
+	explainFlag               = "explain"
+	firstFlag                 = "first"
+	cleanFlag                 = "clean"
+	overrideFlag              = "override"
+	noOverrideFlag            = "no-override"
+	includeFlag               = "include"
+	includeShortFlag          = "i"
+	excludeFlag               = "exclude"
+	fromFlag                  = "from"
+	fromFormatFlag            = "from-format"
+	separatorFlag             = "separator"
+	onConflictFlag            = "on-conflict"
+	toFlag                    = "to"
+	inPlaceFlag               = "in-place"
 )
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/oxio/kvf/internal/dialect"
+	"io"
+	"strings"
+)
+
+const (
+	formatJSON  = "json"
+	formatShell = "shell"
+	formatLines = "lines"
+	formatNul   = "nul"
+)
+
+var outputFormats = []string{formatJSON, formatShell, formatLines, formatNul}
+
+// entry is a key with its value in the output of get and list.
+type entry struct {
+	key   string
+	val   string
+	found bool
+}
+
+func validateFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return newUsageError("invalid format %q, expected one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// writeEntries prints the entries in the format. Entries which are not found
+// are left out of json and shell output, while lines and nul output keep an
+// empty value for them, so the values stay at the position of their keys.
+func writeEntries(w io.Writer, format string, entries []entry) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, entries)
+	case formatShell:
+		return writeShell(w, entries)
+	case formatLines:
+		return writeValues(w, entries, "\n")
+	case formatNul:
+		return writeValues(w, entries, "\x00")
+	default:
+		return validateFormat(format)
+	}
+}
+
+// writeJSON prints the entries as a JSON object, keeping the order of the keys.
+func writeJSON(w io.Writer, entries []entry) error {
+	var b strings.Builder
+	b.WriteString("{")
+	first := true
+	for _, e := range entries {
+		if !e.found {
+			continue
+		}
+		key, err := json.Marshal(e.key)
+		if err != nil {
+			return err
+		}
+		val, err := json.Marshal(e.val)
+		if err != nil {
+			return err
+		}
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(val)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeShell prints the entries as shell variable assignments which are safe
+// to eval.
+func writeShell(w io.Writer, entries []entry) error {
+	for _, e := range entries {
+		if e.found && !dialect.IsShellName(e.key) {
+			return fmt.Errorf("key is not a valid shell variable name: %s", e.key)
+		}
+	}
+
+	for _, e := range entries {
+		if !e.found {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%s=%s\n", e.key, dialect.ShellQuote(e.val))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeValues(w io.Writer, entries []entry, terminator string) error {
+	for _, e := range entries {
+		_, err := io.WriteString(w, e.val+terminator)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,48 +6,125 @@ import (
 	"github.com/oxio/kvf/internal/kvf"
 	"github.com/oxio/kvf/internal/parser"
 	"github.com/spf13/cobra"
+	"slices"
 )
 
 func newGetCmd() *cobra.Command {
 	var defaultVal *string
 	var skipMissingFiles *bool
+	var expand *bool
+	var expandEnv *bool
+	var printVersion *bool
+	var outputFormat *string
+	var explain *bool
+	var first *bool
 
 	cmd := &cobra.Command{
-		Use:   "get <file1> [<file2> <file3> ...] <key> [--default|-d value] [--skip-missing-files|-m]",
-		Short: "Gets a value from the key-value file",
+		Use: "get <file1> [<file2> <file3> ...] <key> [--default|-d value] [--skip-missing-files|-m]" +
+			" | <file1> [<file2> ...] [--format json|shell|lines|nul] -- <key1> [<key2> ...]",
+		Short: "Gets values from the key-value file(s)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 2 {
-				return fmt.Errorf("not enough arguments")
+				return newUsageError("not enough arguments")
 			}
 
-			files := args[:len(args)-1]
-			key := args[len(args)-1]
-
-			var foundItem *parser.Item
-			for _, file := range files {
-				repo := kvf.NewRepo(file, *skipMissingFiles)
+			files, keys := splitFilesAndKeys(cmd, args)
+			if len(files) == 0 || len(keys) == 0 {
+				return newUsageError("not enough arguments")
+			}
+			for _, key := range keys {
+				if "" == key {
+					return parser.ErrEmptyKey
+				}
+			}
 
-				currentItem, err := repo.Get(key)
+			format := *outputFormat
+			if format != "" {
+				err := validateFormat(format)
 				if err != nil {
-					if errors.Is(err, kvf.ErrItemNotFound) {
-						continue
-					}
 					return err
 				}
-				foundItem = currentItem
+			} else if len(keys) > 1 {
+				format = formatLines
 			}
 
-			if foundItem != nil {
-				cmd.Print(foundItem.Val)
-				return nil
+			if *printVersion {
+				if len(files) != 1 || len(keys) != 1 {
+					return newUsageError("--%s requires a single file and a single key", printVersionFlag)
+				}
+
+				item, version, err := kvf.NewRepo(files[0], *skipMissingFiles).GetWithVersion(keys[0])
+				if err != nil && !errors.Is(err, kvf.ErrItemNotFound) {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), version)
+				if item != nil {
+					fmt.Fprint(cmd.OutOrStdout(), item.Val)
+					return nil
+				}
+				if cmd.Flag(defaultValFlag).Changed {
+					fmt.Fprint(cmd.OutOrStdout(), *defaultVal)
+					return nil
+				}
+				return newKeyNotFoundError(keys[0])
+			}
+
+			layers, err := kvf.ReadLayers(files, *skipMissingFiles)
+			if err != nil {
+				return err
+			}
+			items := kvf.Merge(layers, *first)
+
+			var expander *kvf.Expander
+			if *expand || *expandEnv {
+				expander = kvf.NewExpander(items, *expandEnv)
+			}
+
+			values := make(map[string]string, len(items))
+			for _, item := range items {
+				values[item.Key] = item.Val
+			}
+
+			entries := make([]entry, 0, len(keys))
+			var notFound []error
+			for _, key := range keys {
+				e := entry{key: key}
+				e.val, e.found = values[key]
+				if expander != nil {
+					e.val, err = expander.Expand(key)
+					e.found = err == nil
+					if err != nil && !errors.Is(err, kvf.ErrItemNotFound) {
+						return err
+					}
+				}
+				usesDefault := !e.found && cmd.Flag(defaultValFlag).Changed
+				if usesDefault {
+					e.val, e.found = *defaultVal, true
+				}
+				if !e.found {
+					notFound = append(notFound, newKeyNotFoundError(key))
+				}
+				entries = append(entries, e)
+
+				if *explain {
+					explainKey(cmd, layers, key, *first, usesDefault)
+				}
 			}
 
-			if cmd.Flag(defaultValFlag).Changed {
-				cmd.Print(*defaultVal)
+			if format == "" {
+				// A single key is printed as it is, without a line break.
+				if !entries[0].found {
+					return notFound[0]
+				}
+				fmt.Fprint(cmd.OutOrStdout(), entries[0].val)
 				return nil
 			}
 
-			return errors.New("key not found")
+			err = writeEntries(cmd.OutOrStdout(), format, entries)
+			if err != nil {
+				return err
+			}
+			return errors.Join(notFound...)
 		},
 	}
 
@@ -64,10 +141,85 @@ func newGetCmd() *cobra.Command {
 		"Do not issue \"no such file or directory\" error on missing or inaccessible files. Should only be used"+
 			" with multiple files or in combination with the \"--default\" flag.",
 	)
+	expand = cmd.Flags().Bool(
+		expandFlag,
+		false,
+		"Resolve ${VAR}, $VAR, ${VAR:-default} and ${VAR:?message} references against the keys of all provided"+
+			" files. Single-quoted values are not expanded.",
+	)
+	expandEnv = cmd.Flags().Bool(
+		expandEnvFlag,
+		false,
+		"Like --expand, but also resolve references to variables of the process environment which are not found"+
+			" in the files.",
+	)
+	printVersion = cmd.Flags().Bool(
+		printVersionFlag,
+		false,
+		"Print the version of the file on the first line, followed by the value. The version is printed also when"+
+			" the key is not found. Pass it to \"set --"+ifVersionFlag+"\" to update the file only if it has not"+
+			" changed since.",
+	)
+	outputFormat = cmd.Flags().StringP(
+		formatFlag,
+		formatShortFlag,
+		"",
+		"Print the values as a JSON object (json), as KEY='value' lines safe to eval (shell), one per line (lines)"+
+			" or terminated by NUL characters (nul). Missing keys are left out of json and shell output and"+
+			" printed empty in lines and nul output. Defaults to lines for multiple keys.",
+	)
+	explain = cmd.Flags().Bool(
+		explainFlag,
+		false,
+		"Print to stderr which file and line each value comes from, along with the values it takes precedence"+
+			" over.",
+	)
+	first = cmd.Flags().Bool(
+		firstFlag,
+		false,
+		"Take the value from the first file in which the key is found instead of the last one.",
+	)
+	cmd.MarkFlagsMutuallyExclusive(printVersionFlag, formatFlag)
+	cmd.MarkFlagsMutuallyExclusive(printVersionFlag, explainFlag)
+	cmd.MarkFlagsMutuallyExclusive(printVersionFlag, firstFlag)
+	cmd.MarkFlagsMutuallyExclusive(printVersionFlag, expandFlag)
+	cmd.MarkFlagsMutuallyExclusive(printVersionFlag, expandEnvFlag)
 
 	return cmd
 }
 
+// explainKey prints the sources of the key: the file and line of the winning
+// value and of the values it takes precedence over.
+func explainKey(cmd *cobra.Command, layers []*kvf.Layer, key string, first bool, usesDefault bool) {
+	var sources []*kvf.Layer
+	for _, layer := range layers {
+		if layer.Find(key) != nil {
+			sources = append(sources, layer)
+		}
+	}
+	if !first {
+		slices.Reverse(sources)
+	}
+
+	if len(sources) == 0 {
+		if usesDefault {
+			cmd.PrintErrf("%s: not found, using the default value\n", key)
+		} else {
+			cmd.PrintErrf("%s: not found\n", key)
+		}
+		return
+	}
+
+	for i, layer := range sources {
+		item := layer.Find(key)
+		if i == 0 {
+			cmd.PrintErrf("%s: %s:%d: %s\n", key, layer.File, item.Line, item.Val)
+		} else {
+			cmd.PrintErrf("%s: %s:%d: %s (overridden)\n", key, layer.File, item.Line, item.Val)
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(newGetCmd())
 }
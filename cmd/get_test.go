@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"bytes"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"strings"
 	"testing"
 )
 
@@ -223,3 +225,397 @@ func TestNotEnoughArguments(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, string(errContent), "Error: not enough arguments")
 }
+
+func TestGetValue_MultiLine(t *testing.T) {
+	content := `key1=value1
+CERT="-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----"
+key2=value2
+`
+	tmpFile, err := createRandomTestFileWithContent(content)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	testCases := []struct {
+		key           string
+		expectedValue string
+	}{
+		{key: "CERT", expectedValue: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"},
+		{key: "key2", expectedValue: "value2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			cmd := newGetCmd()
+			outBuff := bytes.NewBufferString("")
+			cmd.SetOut(outBuff)
+			cmd.SetArgs([]string{tmpFile.Name(), tc.key})
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, outBuff.String())
+		})
+	}
+}
+
+func TestGetValue_UnterminatedMultiLine(t *testing.T) {
+	content := `key1="value1
+key2=value2
+`
+	tmpFile, err := createRandomTestFileWithContent(content)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd := newGetCmd()
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(bytes.NewBufferString(""))
+	cmd.SetErr(errBuff)
+	cmd.SetArgs([]string{tmpFile.Name(), "key2"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, errBuff.String(), "unterminated quoted value: key1=\"value1")
+}
+
+func TestGetValue_EscapeSequences(t *testing.T) {
+	content := `key1="a\nb\tc"
+key2="say \"hi\" \\o/"
+key3='a\nb'
+key4=a\nb
+key5="unknown \x"
+`
+	tmpFile, err := createRandomTestFileWithContent(content)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	testCases := []struct {
+		key           string
+		expectedValue string
+	}{
+		{key: "key1", expectedValue: "a\nb\tc"},
+		{key: "key2", expectedValue: `say "hi" \o/`},
+		{key: "key3", expectedValue: `a\nb`},
+		{key: "key4", expectedValue: `a\nb`},
+		{key: "key5", expectedValue: `unknown \x`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			cmd := newGetCmd()
+			outBuff := bytes.NewBufferString("")
+			cmd.SetOut(outBuff)
+			cmd.SetArgs([]string{tmpFile.Name(), tc.key})
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, outBuff.String())
+		})
+	}
+}
+
+func TestGetValue_ExportPrefixAndInlineComments(t *testing.T) {
+	content := `export key1=value1
+export	key2 = "value2" # comment
+key3=value3 # comment
+key4=value#4
+key5='value5'   # comment with 'quotes'
+key6="multi
+line" # comment
+exported=foo
+empty= # comment
+`
+	tmpFile, err := createRandomTestFileWithContent(content)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	testCases := []struct {
+		key           string
+		expectedValue string
+	}{
+		{key: "key1", expectedValue: "value1"},
+		{key: "key2", expectedValue: "value2"},
+		{key: "key3", expectedValue: "value3"},
+		{key: "key4", expectedValue: "value#4"},
+		{key: "key5", expectedValue: "value5"},
+		{key: "key6", expectedValue: "multi\nline"},
+		{key: "exported", expectedValue: "foo"},
+		{key: "empty", expectedValue: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			cmd := newGetCmd()
+			outBuff := bytes.NewBufferString("")
+			cmd.SetOut(outBuff)
+			cmd.SetArgs([]string{tmpFile.Name(), tc.key})
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, outBuff.String())
+		})
+	}
+}
+
+func TestGetValue_PrintVersion(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, outBuff, _ := setUpTestGetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "key", "--print-version"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9a-f]{16}\nvalue$", outBuff.String())
+	version, _, _ := strings.Cut(outBuff.String(), "\n")
+
+	cmd, outBuff, _ = setUpTestGetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "missing", "--print-version"})
+	err = cmd.Execute()
+	assert.Equal(t, exitNotFound, exitCode(err))
+	assert.True(t, strings.HasPrefix(outBuff.String(), version+"\n"), "the version should be printed for missing keys too")
+
+	cmd, _, _ = setUpTestGetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), tmpFile.Name(), "key", "--print-version"})
+	err = cmd.Execute()
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestGetValue_MultipleKeys(t *testing.T) {
+	content := "key1=value1\nkey2=\"it's\"\nkey3=\"multi\nline\"\nnot-a-name=x\n"
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+		expectedCode   int
+	}{
+		{
+			name:           "lines by default",
+			args:           []string{"--", "key1", "key2"},
+			expectedOutput: "value1\nit's\n",
+		},
+		{
+			name:           "json",
+			args:           []string{"--format", "json", "--", "key2", "key1", "key3"},
+			expectedOutput: "{\"key2\":\"it's\",\"key1\":\"value1\",\"key3\":\"multi\\nline\"}\n",
+		},
+		{
+			name:           "json of a single key",
+			args:           []string{"key1", "-f", "json"},
+			expectedOutput: "{\"key1\":\"value1\"}\n",
+		},
+		{
+			name:           "shell",
+			args:           []string{"--format", "shell", "--", "key1", "key2", "key3"},
+			expectedOutput: "key1='value1'\nkey2='it'\\''s'\nkey3='multi\nline'\n",
+		},
+		{
+			name:         "shell with a key which is not a valid name",
+			args:         []string{"--format", "shell", "--", "key1", "not-a-name"},
+			expectedCode: exitFailure,
+		},
+		{
+			name:           "nul",
+			args:           []string{"--format", "nul", "--", "key3", "key1"},
+			expectedOutput: "multi\nline\x00value1\x00",
+		},
+		{
+			name:           "missing key in json",
+			args:           []string{"--format", "json", "--", "key1", "missing"},
+			expectedOutput: "{\"key1\":\"value1\"}\n",
+			expectedCode:   exitNotFound,
+		},
+		{
+			name:           "missing key in lines",
+			args:           []string{"--", "missing", "key1"},
+			expectedOutput: "\nvalue1\n",
+			expectedCode:   exitNotFound,
+		},
+		{
+			name:           "default for missing keys",
+			args:           []string{"--format", "json", "--default", "none", "--", "key1", "missing"},
+			expectedOutput: "{\"key1\":\"value1\",\"missing\":\"none\"}\n",
+		},
+		{
+			name:         "invalid format",
+			args:         []string{"--format", "xml", "--", "key1"},
+			expectedCode: exitUsage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(content)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, _ := setUpTestGetCmd()
+			cmd.SilenceUsage = true
+			cmd.SetArgs(append([]string{tmpFile.Name()}, tc.args...))
+			err = cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			if tc.expectedCode != exitUsage && tc.expectedCode != exitFailure {
+				assert.Equal(t, tc.expectedOutput, outBuff.String())
+			}
+		})
+	}
+}
+
+func TestGetValue_MultipleKeys_ReportsEveryMissingKey(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, _, errBuff := setUpTestGetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--", "missing1", "key", "missing2"})
+	err = cmd.Execute()
+	assert.Equal(t, exitNotFound, exitCode(err))
+	assert.Contains(t, errBuff.String(), "key not found: missing1\nkey not found: missing2")
+}
+
+func TestGetValue_First(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=first\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("key=second\nother=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, outBuff, _ := setUpTestGetCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "--first", "--", "key", "other"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nvalue\n", outBuff.String())
+}
+
+func TestGetValue_Explain(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=first\nkey=ignored\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("other=\"multi\nline\"\n# comment\n\nkey=second\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+		expectedErr    string
+	}{
+		{
+			name:           "last wins",
+			args:           []string{"key", "--explain"},
+			expectedOutput: "second",
+			expectedErr: "key: " + testFile2.Name() + ":5: second\n" +
+				"key: " + testFile1.Name() + ":1: first (overridden)\n",
+		},
+		{
+			name:           "first wins",
+			args:           []string{"key", "--explain", "--first"},
+			expectedOutput: "first",
+			expectedErr: "key: " + testFile1.Name() + ":1: first\n" +
+				"key: " + testFile2.Name() + ":5: second (overridden)\n",
+		},
+		{
+			name:           "missing key with default",
+			args:           []string{"--explain", "--default", "none", "--", "missing", "other"},
+			expectedOutput: "none\nmulti\nline\n",
+			expectedErr: "missing: not found, using the default value\n" +
+				"other: " + testFile2.Name() + ":1: multi\nline\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, outBuff, errBuff := setUpTestGetCmd()
+			cmd.SetArgs(append([]string{testFile1.Name(), testFile2.Name()}, tc.args...))
+			err := cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, outBuff.String())
+			assert.Equal(t, tc.expectedErr, errBuff.String())
+		})
+	}
+}
+
+func TestGetValue_Expand(t *testing.T) {
+	content1 := `DB_USER=root
+DB_HOST=localhost
+DATABASE_URL=postgres://${DB_USER}@${DB_HOST}/$DB_NAME
+`
+	content2 := `DB_HOST=db.example.com
+DB_NAME=app
+LITERAL='${DB_HOST}'
+ESCAPED="\${DB_HOST} costs \$5"
+DEFAULT=${MISSING:-${DB_NAME}-default}
+EMPTY=
+EMPTY_DEFAULT=${EMPTY:-fallback}
+UNSET_DEFAULT=${EMPTY-fallback}
+REQUIRED=${MISSING:?must be set}
+CYCLE_A=${CYCLE_B}
+CYCLE_B=$CYCLE_A
+FROM_ENV=${KVF_TEST_EXPAND_VAR:-not from env}
+`
+	tmpFile1, err := createRandomTestFileWithContent(content1)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile1.Name())
+
+	tmpFile2, err := createRandomTestFileWithContent(content2)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile2.Name())
+
+	t.Setenv("KVF_TEST_EXPAND_VAR", "from env")
+
+	testCases := []struct {
+		key           string
+		flags         []string
+		expectedValue string
+		expectedError string
+	}{
+		{key: "DATABASE_URL", expectedValue: "postgres://root@db.example.com/app"},
+		{key: "LITERAL", expectedValue: "${DB_HOST}"},
+		{key: "ESCAPED", expectedValue: "${DB_HOST} costs $5"},
+		{key: "DEFAULT", expectedValue: "app-default"},
+		{key: "EMPTY_DEFAULT", expectedValue: "fallback"},
+		{key: "UNSET_DEFAULT", expectedValue: ""},
+		{key: "REQUIRED", expectedError: "required value is not set: MISSING: must be set"},
+		{key: "CYCLE_A", expectedError: "reference cycle: CYCLE_A -> CYCLE_B -> CYCLE_A"},
+		{key: "FROM_ENV", expectedValue: "not from env"},
+		{key: "FROM_ENV", flags: []string{"--expand-env"}, expectedValue: "from env"},
+		{key: "MISSING", flags: []string{"-d", "default"}, expectedValue: "default"},
+		{key: "MISSING", expectedError: "key not found: MISSING"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			cmd := newGetCmd()
+			outBuff := bytes.NewBufferString("")
+			errBuff := bytes.NewBufferString("")
+			cmd.SetOut(outBuff)
+			cmd.SetErr(errBuff)
+			args := []string{tmpFile1.Name(), tmpFile2.Name(), tc.key, "--expand"}
+			cmd.SetArgs(append(args, tc.flags...))
+
+			err := cmd.Execute()
+			if tc.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, errBuff.String(), tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, outBuff.String())
+		})
+	}
+}
+
+func setUpTestGetCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newGetCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/oxio/kvf/internal/parser"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sourceJSON = "json"
+	sourceYAML = "yaml"
+	sourceEnv  = "env"
+)
+
+var mergePolicies = map[string]kvf.MergePolicy{
+	"overwrite": kvf.Overwrite,
+	"keep":      kvf.KeepExisting,
+	"fail":      kvf.FailOnConflict,
+}
+
+func newImportCmd() *cobra.Command {
+	var from *string
+	var fromFormat *string
+	var separator *string
+	var onConflict *string
+
+	cmd := &cobra.Command{
+		Use:   "import <file> --from <source>|- [--from-format json|yaml|env] [--separator __] [--on-conflict overwrite|keep|fail]",
+		Short: "Imports keys from a JSON, YAML or key-value file",
+		Long: "Imports keys from a JSON, YAML or key-value file. Nested objects are flattened into keys joined with" +
+			" the separator, e.g. {\"db\": {\"host\": \"x\"}} becomes db__host=x. All keys are written in a single" +
+			" transaction.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return newUsageError("expected a single target file")
+			}
+			if *from == "" {
+				return newUsageError("--%s is required", fromFlag)
+			}
+
+			policy, ok := mergePolicies[*onConflict]
+			if !ok {
+				return newUsageError("invalid --%s value %q, expected overwrite, keep or fail", onConflictFlag, *onConflict)
+			}
+
+			format := *fromFormat
+			if format == "" {
+				format = sourceFormatOf(*from)
+			}
+			if format != sourceJSON && format != sourceYAML && format != sourceEnv {
+				return newUsageError("invalid --%s value %q, expected json, yaml or env", fromFormatFlag, format)
+			}
+
+			items, err := readSource(cmd, *from, format, *separator)
+			if err != nil {
+				return err
+			}
+
+			return kvf.NewRepo(args[0], false).Import(policy, items...)
+		},
+	}
+
+	from = cmd.Flags().String(
+		fromFlag,
+		"",
+		"The file to import, \"-\" reads it from stdin.",
+	)
+	fromFormat = cmd.Flags().String(
+		fromFormatFlag,
+		"",
+		"The format of the imported file: json, yaml or env. Defaults to the format matching its extension, or env.",
+	)
+	separator = cmd.Flags().String(
+		separatorFlag,
+		"__",
+		"The separator joining the keys of nested objects, e.g. \".\" for db.host.",
+	)
+	onConflict = cmd.Flags().String(
+		onConflictFlag,
+		"overwrite",
+		"What to do with keys which are already set: overwrite them, keep the existing values or fail, leaving the"+
+			" file untouched, when a value differs.",
+	)
+
+	return cmd
+}
+
+func sourceFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return sourceJSON
+	case ".yaml", ".yml":
+		return sourceYAML
+	default:
+		return sourceEnv
+	}
+}
+
+func readSource(cmd *cobra.Command, from string, format string, separator string) ([]*parser.Item, error) {
+	if format == sourceEnv && from != stdinArg {
+		items, err := kvf.ResolveAll([]string{from}, false)
+		if err != nil {
+			return nil, err
+		}
+		imported := make([]*parser.Item, 0, len(items))
+		for _, item := range items {
+			// The items are made anew, so they are quoted as set would do.
+			newItem, err := parser.NewItem(item.Key, item.Val)
+			if err != nil {
+				return nil, err
+			}
+			imported = append(imported, newItem)
+		}
+		return imported, nil
+	}
+
+	var r io.Reader = cmd.InOrStdin()
+	if from != stdinArg {
+		fp, err := os.Open(from)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = fp.Close()
+		}()
+		r = fp
+	}
+
+	switch format {
+	case sourceJSON:
+		return kvf.FlattenJSON(r, separator)
+	case sourceYAML:
+		return kvf.FlattenYAML(r, separator)
+	default:
+		items, err := readBatch(r)
+		if err != nil {
+			return nil, err
+		}
+		return firstOccurrences(items), nil
+	}
+}
+
+// firstOccurrences drops repeated keys, keeping the value get would return.
+func firstOccurrences(items []*parser.Item) []*parser.Item {
+	seen := make(map[string]bool, len(items))
+	unique := make([]*parser.Item, 0, len(items))
+	for _, item := range items {
+		if !seen[item.Key] {
+			seen[item.Key] = true
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+func init() {
+	rootCmd.AddCommand(newImportCmd())
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	jsonContent := `{"db": {"host": "localhost", "port": 5432, "ssl": false, "password": null},
+		"hosts": ["a", "b"], "greeting": "hello world", "price": 1.50}`
+	yamlContent := "defaults: &defaults\n  host: localhost\n  port: 5432\n" +
+		"db:\n  <<: *defaults\n  port: 6543\n  name: \"app\"\nlist:\n  - a\n  - ~\n"
+
+	testCases := []struct {
+		name            string
+		sourceName      string
+		source          string
+		args            []string
+		stdin           string
+		originalContent string
+		expectedContent string
+		expectedCode    int
+	}{
+		{
+			name:       "json",
+			sourceName: "secrets.json",
+			source:     jsonContent,
+			expectedContent: "db__host=localhost\ndb__port=5432\ndb__ssl=false\ndb__password=\n" +
+				"hosts__0=a\nhosts__1=b\ngreeting=hello world\nprice=1.50\n",
+		},
+		{
+			name:            "json with separator",
+			sourceName:      "secrets.json",
+			source:          `{"db": {"host": "localhost", "port": 5432}}`,
+			args:            []string{"--separator", "."},
+			expectedContent: "db.host=localhost\ndb.port=5432\n",
+		},
+		{
+			name:       "yaml",
+			sourceName: "config.yaml",
+			source:     yamlContent,
+			expectedContent: "defaults__host=localhost\ndefaults__port=5432\n" +
+				"db__port=6543\ndb__name=app\ndb__host=localhost\nlist__0=a\nlist__1=\n",
+		},
+		{
+			name:            "env",
+			sourceName:      "other.env",
+			source:          "# comment\nKEY1=value1\nKEY2=\"multi\nline\"\nKEY1=ignored\n",
+			expectedContent: "KEY1=value1\nKEY2=\"multi\nline\"\n",
+		},
+		{
+			name:            "stdin",
+			args:            []string{"--from-format", "json"},
+			stdin:           `{"key": "value"}`,
+			expectedContent: "key=value\n",
+		},
+		{
+			name:            "stdin defaults to env",
+			stdin:           "KEY=value\n",
+			expectedContent: "KEY=value\n",
+		},
+		{
+			name:            "overwrite",
+			sourceName:      "other.env",
+			source:          "KEY1=new\nKEY2=new\n",
+			originalContent: "# existing\nKEY1=old\n",
+			expectedContent: "# existing\nKEY1=new\nKEY2=new\n",
+		},
+		{
+			name:            "keep existing",
+			sourceName:      "other.env",
+			source:          "KEY1=new\nKEY2=new\n",
+			args:            []string{"--on-conflict", "keep"},
+			originalContent: "KEY1=old\n",
+			expectedContent: "KEY1=old\nKEY2=new\n",
+		},
+		{
+			name:            "fail on conflict",
+			sourceName:      "other.env",
+			source:          "KEY1=new\nKEY2=new\n",
+			args:            []string{"--on-conflict", "fail"},
+			originalContent: "KEY1=old\n",
+			expectedContent: "KEY1=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "fail on conflict with equal values",
+			sourceName:      "other.env",
+			source:          "KEY1=old\nKEY2=new\n",
+			args:            []string{"--on-conflict", "fail"},
+			originalContent: "KEY1=old\n",
+			expectedContent: "KEY1=old\nKEY2=new\n",
+		},
+		{
+			name:            "not an object",
+			sourceName:      "list.json",
+			source:          `["a"]`,
+			originalContent: "KEY=old\n",
+			expectedContent: "KEY=old\n",
+			expectedCode:    exitFailure,
+		},
+		{
+			name:            "invalid key",
+			sourceName:      "invalid.json",
+			source:          `{"a key": "value"}`,
+			originalContent: "KEY=old\n",
+			expectedContent: "KEY=old\n",
+			expectedCode:    exitFailure,
+		},
+		{
+			name:            "duplicate key after flattening",
+			sourceName:      "duplicate.json",
+			source:          `{"a__b": "1", "a": {"b": "2"}}`,
+			originalContent: "KEY=old\n",
+			expectedContent: "KEY=old\n",
+			expectedCode:    exitFailure,
+		},
+		{
+			name:            "invalid policy",
+			sourceName:      "other.env",
+			source:          "KEY=new\n",
+			args:            []string{"--on-conflict", "merge"},
+			originalContent: "KEY=old\n",
+			expectedContent: "KEY=old\n",
+			expectedCode:    exitUsage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "target.env")
+			if tc.originalContent != "" {
+				assert.NoError(t, os.WriteFile(target, []byte(tc.originalContent), 0644))
+			}
+
+			from := "-"
+			if tc.sourceName != "" {
+				from = filepath.Join(dir, tc.sourceName)
+				assert.NoError(t, os.WriteFile(from, []byte(tc.source), 0644))
+			}
+
+			cmd, _, _ := setUpTestImportCmd()
+			cmd.SetIn(strings.NewReader(tc.stdin))
+			cmd.SetArgs(append([]string{target, "--from", from}, tc.args...))
+			err := cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			assertFileContentEquals(t, target, tc.expectedContent)
+		})
+	}
+}
+
+func setUpTestImportCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newImportCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+func newIncrCmd() *cobra.Command {
+	return newCounterCmd("incr", "Increments an integer value in the key-value file", 1)
+}
+
+func newDecrCmd() *cobra.Command {
+	return newCounterCmd("decr", "Decrements an integer value in the key-value file", -1)
+}
+
+// newCounterCmd creates a command adding to an integer value. The sign is
+// applied to the --by value.
+func newCounterCmd(name string, short string, sign int64) *cobra.Command {
+	var by *int64
+	var initVal *int64
+	var minVal *int64
+	var maxVal *int64
+	var wrap *bool
+
+	cmd := &cobra.Command{
+		Use:   name + " <file> <key> [--by N] [--init V] [--min N] [--max N] [--wrap]",
+		Short: short,
+		Long: short + " and prints the new value. The value is read and written back while the file is locked," +
+			" so concurrent updates are never lost.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return newUsageError("expected <file> <key>")
+			}
+			if *wrap && !(cmd.Flag(minFlag).Changed && cmd.Flag(maxFlag).Changed) {
+				return newUsageError("--%s requires both --%s and --%s", wrapFlag, minFlag, maxFlag)
+			}
+
+			counter := kvf.Counter{
+				By:   sign * *by,
+				Init: *initVal,
+				Wrap: *wrap,
+			}
+			if cmd.Flag(minFlag).Changed {
+				counter.Min = minVal
+			}
+			if cmd.Flag(maxFlag).Changed {
+				counter.Max = maxVal
+			}
+			if counter.Min != nil && counter.Max != nil && *counter.Min > *counter.Max {
+				return newUsageError("--%s is greater than --%s", minFlag, maxFlag)
+			}
+
+			val, err := kvf.NewRepo(args[0], false).Increment(args[1], counter)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), strconv.FormatInt(val, 10))
+			return nil
+		},
+	}
+
+	by = cmd.Flags().Int64(byFlag, 1, "The amount to "+name+" by.")
+	initVal = cmd.Flags().Int64(
+		initFlag,
+		0,
+		"The value of the key when it is not set yet. The key is then set to this value with the change applied.",
+	)
+	minVal = cmd.Flags().Int64(minFlag, 0, "The lowest allowed value. Exceeding it fails with exit code 5.")
+	maxVal = cmd.Flags().Int64(maxFlag, 0, "The highest allowed value. Exceeding it fails with exit code 5.")
+	wrap = cmd.Flags().Bool(
+		wrapFlag,
+		false,
+		"Continue from the other bound instead of failing when a bound is exceeded. Requires both --"+minFlag+
+			" and --"+maxFlag+".",
+	)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newIncrCmd())
+	rootCmd.AddCommand(newDecrCmd())
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestIncr(t *testing.T) {
+	testCases := []struct {
+		name            string
+		decr            bool
+		originalContent string
+		args            []string
+		expectedOutput  string
+		expectedContent string
+		expectedCode    int
+	}{
+		{
+			name:            "increment",
+			originalContent: "# build\nbuild=41\n",
+			args:            []string{"build"},
+			expectedOutput:  "42",
+			expectedContent: "# build\nbuild=42\n",
+		},
+		{
+			name:            "decrement",
+			decr:            true,
+			originalContent: "retries=3\n",
+			args:            []string{"retries"},
+			expectedOutput:  "2",
+			expectedContent: "retries=2\n",
+		},
+		{
+			name:            "by",
+			originalContent: "build=41\n",
+			args:            []string{"build", "--by", "10"},
+			expectedOutput:  "51",
+			expectedContent: "build=51\n",
+		},
+		{
+			name:            "missing key",
+			originalContent: "other=x\n",
+			args:            []string{"build"},
+			expectedOutput:  "1",
+			expectedContent: "other=x\nbuild=1\n",
+		},
+		{
+			name:            "missing key with init",
+			decr:            true,
+			originalContent: "",
+			args:            []string{"retries", "--init", "5"},
+			expectedOutput:  "4",
+			expectedContent: "retries=4\n",
+		},
+		{
+			name:            "keeps quotes and comments",
+			originalContent: "export build=\"41\" # build number\n",
+			args:            []string{"build"},
+			expectedOutput:  "42",
+			expectedContent: "export build=\"42\" # build number\n",
+		},
+		{
+			name:            "not a number",
+			originalContent: "build=abc\n",
+			args:            []string{"build"},
+			expectedContent: "build=abc\n",
+			expectedCode:    exitFailure,
+		},
+		{
+			name:            "max exceeded",
+			originalContent: "build=9\n",
+			args:            []string{"build", "--max", "9"},
+			expectedContent: "build=9\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "min exceeded",
+			decr:            true,
+			originalContent: "retries=0\n",
+			args:            []string{"retries", "--min", "0"},
+			expectedContent: "retries=0\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "wrap at max",
+			originalContent: "slot=9\n",
+			args:            []string{"slot", "--min", "0", "--max", "9", "--wrap"},
+			expectedOutput:  "0",
+			expectedContent: "slot=0\n",
+		},
+		{
+			name:            "wrap at min",
+			decr:            true,
+			originalContent: "slot=1\n",
+			args:            []string{"slot", "--by", "3", "--min", "0", "--max", "9", "--wrap"},
+			expectedOutput:  "8",
+			expectedContent: "slot=8\n",
+		},
+		{
+			name:            "int64 overflow",
+			originalContent: "build=9223372036854775807\n",
+			args:            []string{"build"},
+			expectedContent: "build=9223372036854775807\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "wrap without bounds",
+			originalContent: "slot=1\n",
+			args:            []string{"slot", "--max", "9", "--wrap"},
+			expectedContent: "slot=1\n",
+			expectedCode:    exitUsage,
+		},
+		{
+			name:            "min greater than max",
+			originalContent: "slot=1\n",
+			args:            []string{"slot", "--min", "9", "--max", "0"},
+			expectedContent: "slot=1\n",
+			expectedCode:    exitUsage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(tc.originalContent)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, _ := setUpTestIncrCmd(tc.decr)
+			cmd.SetArgs(append([]string{tmpFile.Name()}, tc.args...))
+			err = cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			if tc.expectedCode == exitOK {
+				assert.Equal(t, tc.expectedOutput, outBuff.String())
+			}
+			assertFileContentEquals(t, tmpFile.Name(), tc.expectedContent)
+		})
+	}
+}
+
+func TestIncr_Concurrent(t *testing.T) {
+	filePath := getRandomTestFilePath()
+	defer removeTestFile(filePath)
+
+	var wg sync.WaitGroup
+	outputs := make([]string, 100)
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cmd, outBuff, _ := setUpTestIncrCmd(false)
+			cmd.SetArgs([]string{filePath, "counter"})
+			assert.NoError(t, cmd.Execute())
+			outputs[i] = outBuff.String()
+		}(i)
+	}
+	wg.Wait()
+
+	assertFileContentEquals(t, filePath, "counter=100\n")
+	seen := make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		seen[output] = true
+	}
+	for i := 1; i <= len(outputs); i++ {
+		assert.True(t, seen[strconv.Itoa(i)], "every increment should print a distinct value")
+	}
+}
+
+func setUpTestIncrCmd(decr bool) (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newIncrCmd()
+	if decr {
+		cmd = newDecrCmd()
+	}
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/spf13/cobra"
+	"sort"
+)
+
+func newListCmd() *cobra.Command {
+	var keysOnly *bool
+	var withValues *bool
+	var globs *[]string
+	var patterns *[]string
+	var sortKeys *bool
+	var skipMissingFiles *bool
+	var expand *bool
+	var expandEnv *bool
+	var outputFormat *string
+
+	cmd := &cobra.Command{
+		Use: "list <file1> [<file2> <file3> ...] [--with-values|-v] [--glob|-g pattern] [--regex|-r pattern] [--sort|-s]" +
+			" [--format|-f json|shell|lines|nul]",
+		Aliases: []string{"ls"},
+		Short:   "Lists keys resolved from the key-value file(s)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return newUsageError("not enough arguments")
+			}
+
+			filter, err := newKeyFilter(*globs, *patterns)
+			if err != nil {
+				return err
+			}
+
+			items, err := kvf.ResolveAll(args, *skipMissingFiles)
+			if err != nil {
+				return err
+			}
+
+			if *sortKeys {
+				sort.SliceStable(items, func(i, j int) bool {
+					return items[i].Key < items[j].Key
+				})
+			}
+
+			var expander *kvf.Expander
+			if *expand || *expandEnv {
+				expander = kvf.NewExpander(items, *expandEnv)
+			}
+
+			format := formatLines
+			if *outputFormat != "" {
+				format = *outputFormat
+				err = validateFormat(format)
+				if err != nil {
+					return err
+				}
+			}
+			// JSON and shell output always have the values.
+			printValues := (*withValues && !*keysOnly) || format == formatJSON || format == formatShell
+
+			entries := make([]entry, 0, len(items))
+			for _, item := range items {
+				if !filter.Match(item.Key) {
+					continue
+				}
+				e := entry{key: item.Key, found: true}
+				if printValues {
+					e.val = item.Val
+					if expander != nil {
+						e.val, err = expander.Expand(item.Key)
+						if err != nil {
+							return err
+						}
+					}
+				}
+				entries = append(entries, e)
+			}
+
+			if format == formatJSON || format == formatShell {
+				return writeEntries(cmd.OutOrStdout(), format, entries)
+			}
+
+			terminator := "\n"
+			if format == formatNul {
+				terminator = "\x00"
+			}
+			for _, e := range entries {
+				if printValues {
+					fmt.Fprint(cmd.OutOrStdout(), e.key+"="+e.val+terminator)
+				} else {
+					fmt.Fprint(cmd.OutOrStdout(), e.key+terminator)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	keysOnly = cmd.Flags().BoolP(keysOnlyFlag, keysOnlyShortFlag, false, "Print only the keys. This is the default.")
+	withValues = cmd.Flags().BoolP(withValuesFlag, withValuesShortFlag, false, "Print keys with their values as key=value.")
+	globs = cmd.Flags().StringArrayP(
+		globFlag,
+		globShortFlag,
+		nil,
+		"Only list keys matching the glob pattern. Can be repeated.",
+	)
+	patterns = cmd.Flags().StringArrayP(
+		regexFlag,
+		regexShortFlag,
+		nil,
+		"Only list keys matching the regular expression. Can be repeated.",
+	)
+	sortKeys = cmd.Flags().BoolP(sortFlag, sortShortFlag, false, "Sort keys alphabetically instead of by first appearance.")
+	skipMissingFiles = cmd.Flags().BoolP(
+		skipMissingFilesFlag,
+		skipMissingFilesShortFlag,
+		false,
+		"Do not issue \"no such file or directory\" error on missing or inaccessible files.",
+	)
+	expand = cmd.Flags().Bool(
+		expandFlag,
+		false,
+		"Resolve ${VAR}, $VAR, ${VAR:-default} and ${VAR:?message} references in the printed values.",
+	)
+	expandEnv = cmd.Flags().Bool(
+		expandEnvFlag,
+		false,
+		"Like --expand, but also resolve references to variables of the process environment which are not found"+
+			" in the files.",
+	)
+	outputFormat = cmd.Flags().StringP(
+		formatFlag,
+		formatShortFlag,
+		"",
+		"Print the keys with their values as a JSON object (json) or as KEY='value' lines safe to eval (shell), or"+
+			" print the keys, or key=value with --with-values, one per line (lines) or terminated by NUL characters"+
+			" (nul). Defaults to lines.",
+	)
+	cmd.MarkFlagsMutuallyExclusive(keysOnlyFlag, withValuesFlag)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newListCmd())
+}
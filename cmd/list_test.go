@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestListKeys_Success(t *testing.T) {
+	content1 := `APP_ENV=prod
+APP_PORT=8000
+# comment
+
+DB_HOST=localhost
+`
+	content2 := `DB_USER=root
+APP_ENV=dev
+`
+	tmpFile1, err := createRandomTestFileWithContent(content1)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile1.Name())
+
+	tmpFile2, err := createRandomTestFileWithContent(content2)
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile2.Name())
+
+	testCases := []struct {
+		name           string
+		flags          []string
+		expectedOutput string
+	}{
+		{
+			name:           "keys in order of first appearance",
+			expectedOutput: "APP_ENV\nAPP_PORT\nDB_HOST\nDB_USER\n",
+		},
+		{
+			name:           "keys only",
+			flags:          []string{"--keys-only"},
+			expectedOutput: "APP_ENV\nAPP_PORT\nDB_HOST\nDB_USER\n",
+		},
+		{
+			name:           "with values uses last file wins",
+			flags:          []string{"--with-values"},
+			expectedOutput: "APP_ENV=dev\nAPP_PORT=8000\nDB_HOST=localhost\nDB_USER=root\n",
+		},
+		{
+			name:           "sorted",
+			flags:          []string{"--sort", "-v"},
+			expectedOutput: "APP_ENV=dev\nAPP_PORT=8000\nDB_HOST=localhost\nDB_USER=root\n",
+		},
+		{
+			name:           "glob filter",
+			flags:          []string{"--glob", "DB_*"},
+			expectedOutput: "DB_HOST\nDB_USER\n",
+		},
+		{
+			name:           "regex filter",
+			flags:          []string{"--regex", "PORT$", "--regex", "^DB_U"},
+			expectedOutput: "APP_PORT\nDB_USER\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, outBuff, _ := setUpTestListCmd()
+			cmd.SetArgs(append([]string{tmpFile1.Name(), tmpFile2.Name()}, tc.flags...))
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+
+			outContent, err := io.ReadAll(outBuff)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, string(outContent))
+		})
+	}
+}
+
+func TestListKeys_SortIsStableAcrossFiles(t *testing.T) {
+	tmpFile1, err := createRandomTestFileWithContent("b=1\na=1\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile1.Name())
+
+	tmpFile2, err := createRandomTestFileWithContent("c=2\nb=2\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile2.Name())
+
+	cmd, outBuff, _ := setUpTestListCmd()
+	cmd.SetArgs([]string{tmpFile1.Name(), tmpFile2.Name(), "-s", "-v"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	outContent, err := io.ReadAll(outBuff)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1\nb=2\nc=2\n", string(outContent))
+}
+
+func TestListKeys_Expand(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("HOST=localhost\nURL=http://${HOST}:${PORT:-80}\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, outBuff, _ := setUpTestListCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--with-values", "--expand"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "HOST=localhost\nURL=http://localhost:80\n", outBuff.String())
+}
+
+func TestListKeys_InvalidRegex(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, _, errBuff := setUpTestListCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "--regex", "("})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+
+	errContent, err := io.ReadAll(errBuff)
+	assert.NoError(t, err)
+	assert.Contains(t, string(errContent), "invalid regex")
+}
+
+func TestListKeys_MissingFile(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	cmd, _, errBuff := setUpTestListCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "non-existing-file"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	errContent, err := io.ReadAll(errBuff)
+	assert.NoError(t, err)
+	assert.Contains(t, string(errContent), "no such file or directory")
+
+	cmd, outBuff, _ := setUpTestListCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "non-existing-file", "-m"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	outContent, err := io.ReadAll(outBuff)
+	assert.NoError(t, err)
+	assert.Equal(t, "key\n", string(outContent))
+}
+
+func TestList_Format(t *testing.T) {
+	content := "key1=value1\nkey2=\"it's\"\n"
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "json",
+			args:           []string{"--format", "json"},
+			expectedOutput: "{\"key1\":\"value1\",\"key2\":\"it's\"}\n",
+		},
+		{
+			name:           "shell",
+			args:           []string{"-f", "shell"},
+			expectedOutput: "key1='value1'\nkey2='it'\\''s'\n",
+		},
+		{
+			name:           "nul keys",
+			args:           []string{"--format", "nul"},
+			expectedOutput: "key1\x00key2\x00",
+		},
+		{
+			name:           "nul with values",
+			args:           []string{"--format", "nul", "--with-values"},
+			expectedOutput: "key1=value1\x00key2=it's\x00",
+		},
+		{
+			name:           "lines",
+			args:           []string{"--format", "lines", "--glob", "*2"},
+			expectedOutput: "key2\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent(content)
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, outBuff, _ := setUpTestListCmd()
+			cmd.SetArgs(append([]string{tmpFile.Name()}, tc.args...))
+			err = cmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, outBuff.String())
+		})
+	}
+}
+
+func setUpTestListCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := newListCmd()
+	outBuff := bytes.NewBufferString("")
+	errBuff := bytes.NewBufferString("")
+	cmd.SetOut(outBuff)
+	cmd.SetErr(errBuff)
+
+	return cmd, outBuff, errBuff
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+)
+
+func newLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock <file1> [<file2> <file3> ...] -- <command> [<args> ...]",
+		Short: "Runs a command while holding the lock of the file(s)",
+		Long: "Runs a command while holding the lock of the file(s), so that no other kvf process can access them" +
+			" until the command exits. kvf commands run by the command don't wait for the held locks, but still" +
+			" serialize among themselves. The exit code of the command is passed through.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 1 || dash == len(args) {
+				return newUsageError("expected <file>... -- <command>")
+			}
+			files, command := args[:dash], args[dash:]
+
+			locks, err := lock.NewAll(files)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				for _, l := range locks {
+					_ = l.Release()
+				}
+			}()
+
+			env, err := lock.Environ(locks)
+			if err != nil {
+				return err
+			}
+
+			child := exec.Command(command[0], command[1:]...)
+			child.Env = append(os.Environ(), env...)
+			child.Stdin = cmd.InOrStdin()
+			child.Stdout = cmd.OutOrStdout()
+			child.Stderr = cmd.ErrOrStderr()
+
+			return runChild(cmd, child)
+		},
+	}
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newLockCmd())
+}
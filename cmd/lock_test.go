@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// kvfCommand returns the command running kvf as a child process of the lock
+// command.
+func kvfCommand(t *testing.T, args ...string) []string {
+	t.Setenv(runRootCmdEnv, "1")
+	return append([]string{os.Args[0]}, args...)
+}
+
+func TestLock_NestedCommands(t *testing.T) {
+	// Fail instead of hanging if a nested command waits for the held lock.
+	t.Setenv(lock.TimeoutEnv, "2s")
+
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	args := append([]string{"lock", tmpFile.Name(), "--"}, kvfCommand(t, "set", tmpFile.Name(), "key", "new")...)
+	err = executeRootCmd(t, io.Discard, args...)
+	assert.NoError(t, err)
+	assertFileContentEquals(t, tmpFile.Name(), "key=new\n")
+}
+
+func TestLock_HoldsLock(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	// Without the inherited locks a nested command competes for the lock.
+	child := append([]string{"env", "-u", lock.HeldEnv}, kvfCommand(t, "get", tmpFile.Name(), "key", "--no-wait")...)
+	err = executeRootCmd(t, io.Discard, append([]string{"lock", tmpFile.Name(), "--"}, child...)...)
+	assert.Equal(t, exitLockTimeout, exitCode(err))
+
+	l, err := lock.New(tmpFile.Name())
+	assert.NoError(t, err, "the lock should be released when the command exits")
+	assert.NoError(t, l.Release())
+}
+
+func TestLock_SpoofedTokenIsIgnored(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	// The nested command is passed the held lock with a token of its own.
+	get := strings.Join(kvfCommand(t, "get", tmpFile.Name(), "key", "--no-wait"), " ")
+	script := `KVF_HELD_LOCKS=$(printf %s "$KVF_HELD_LOCKS" | sed 's/^[0-9a-f]*=/spoofed=/') exec ` + get
+	err = executeRootCmd(t, io.Discard, "lock", tmpFile.Name(), "--", "sh", "-c", script)
+	assert.Equal(t, exitLockTimeout, exitCode(err))
+}
+
+func TestLock_TokenExpiresWithLock(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+	envFile := filepath.Join(t.TempDir(), "env")
+
+	err = executeRootCmd(t, io.Discard, "lock", tmpFile.Name(), "--",
+		"sh", "-c", `printf %s "$KVF_HELD_LOCKS" > `+envFile)
+	assert.NoError(t, err)
+	held, err := os.ReadFile(envFile)
+	assert.NoError(t, err)
+
+	// A process left behind by the command can't use the lock once the lock
+	// command has released it.
+	l, err := lock.New(tmpFile.Name())
+	assert.NoError(t, err)
+	defer func() { _ = l.Release() }()
+	t.Setenv(lock.HeldEnv, string(held))
+	err = executeRootCmd(t, io.Discard, "get", tmpFile.Name(), "key", "--no-wait")
+	assert.Equal(t, exitLockTimeout, exitCode(err))
+}
+
+func TestLock_NestedWritersSerialize(t *testing.T) {
+	t.Setenv(lock.TimeoutEnv, "10s")
+
+	tmpFile, err := createRandomTestFileWithContent("counter=0\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	incr := strings.Join(kvfCommand(t, "incr", tmpFile.Name(), "counter"), " ")
+	script := "for i in $(seq 20); do " + incr + " > /dev/null & done; wait"
+	err = executeRootCmd(t, io.Discard, "lock", tmpFile.Name(), "--", "sh", "-c", script)
+	assert.NoError(t, err)
+	assertFileContentEquals(t, tmpFile.Name(), "counter=20\n")
+}
+
+func TestLock_ExitCode(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	err = executeRootCmd(t, io.Discard, "lock", tmpFile.Name(), "--", "sh", "-c", "exit 7")
+	assert.Equal(t, 7, exitCode(err))
+}
+
+func TestLock_Output(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	outBuff := bytes.NewBufferString("")
+	rootCmd.SetOut(outBuff)
+	defer rootCmd.SetOut(nil)
+	rootCmd.SetArgs([]string{"lock", tmpFile.Name(), "--", "echo", "hello"})
+	err = rootCmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", outBuff.String())
+}
+
+func TestLock_Usage(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "no separator", args: []string{"file", "echo"}},
+		{name: "no files", args: []string{"--", "echo"}},
+		{name: "no command", args: []string{"file", "--"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := newLockCmd()
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+			err := cmd.Execute()
+			assert.Equal(t, exitUsage, exitCode(err))
+		})
+	}
+}
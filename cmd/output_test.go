@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"testing"
+)
+
+// Cobra's Print functions write to stderr unless an output is set, so the
+// commands are run with the real stdout to check that values can be piped.
+func TestValuesArePrintedToStdout(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	testCases := []struct {
+		name           string
+		cmd            *cobra.Command
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "get",
+			cmd:            newGetCmd(),
+			args:           []string{tmpFile.Name(), "key"},
+			expectedOutput: "value",
+		},
+		{
+			name:           "list",
+			cmd:            newListCmd(),
+			args:           []string{tmpFile.Name(), "--with-values"},
+			expectedOutput: "key=value\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errBuff := bytes.NewBufferString("")
+			tc.cmd.SetErr(errBuff)
+			tc.cmd.SetArgs(tc.args)
+
+			stdout, err := captureStdout(t, tc.cmd.Execute)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, stdout)
+			assert.Empty(t, errBuff.String())
+		})
+	}
+}
+
+func captureStdout(t *testing.T, run func() error) (string, error) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := run()
+	os.Stdout = stdout
+	assert.NoError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(out), runErr
+}
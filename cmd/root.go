@@ -1,15 +1,107 @@
 package cmd
 
 import (
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/oxio/kvf/internal/parser"
 	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"time"
 )
 
 var rootCmd = &cobra.Command{
 	Use:     "kvf",
 	Short:   "Simple Key-Value storage tool",
 	Version: "1.2.2",
+	// The usage is only printed for usage errors, by Execute.
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed(lockDirFlag) {
+			lock.SetDir(lockDirVal)
+		}
+		if cmd.Flags().Changed(lockSidecarFlag) {
+			lock.SetSidecar(lockSidecarVal)
+		}
+		if cmd.Flags().Changed(lockTimeoutFlag) {
+			lock.SetTimeout(lockTimeoutVal)
+		}
+		if cmd.Flags().Changed(noWaitFlag) {
+			lock.SetNoWait(noWaitVal)
+		}
+		if cmd.Flags().Changed(fileFormatFlag) {
+			codec, err := parser.GetCodec(fileFormatVal)
+			if err != nil {
+				return &usageError{err: err}
+			}
+			kvf.SetFormat(codec)
+		}
+		return nil
+	},
 }
 
+var (
+	lockDirVal     string
+	lockSidecarVal bool
+	lockTimeoutVal time.Duration
+	noWaitVal      bool
+	fileFormatVal  string
+)
+
 func Execute() {
-	_ = rootCmd.Execute()
+	c, err := rootCmd.ExecuteC()
+	if err != nil && c == rootCmd {
+		// The root command has nothing to run by itself, so any error it
+		// reports is an unknown command or an invalid flag.
+		err = &usageError{err: err}
+	}
+	code := exitCode(err)
+	if code == exitUsage {
+		c.PrintErrln(c.UsageString())
+	}
+	os.Exit(code)
+}
+
+func init() {
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+
+	rootCmd.PersistentFlags().StringVar(
+		&lockDirVal,
+		lockDirFlag,
+		"",
+		"Directory for lock files. Defaults to the "+lock.DirEnv+" environment variable, or the first writable of"+
+			" /var/lock/kvf, $XDG_RUNTIME_DIR/kvf and a per-user temporary directory.",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&lockSidecarVal,
+		lockSidecarFlag,
+		false,
+		"Lock \"<file>.lock\" next to each file instead of using the lock directory, so that processes which don't"+
+			" share the lock directory, e.g. containers sharing a volume, coordinate. \"<file>.lock.gate\", which"+
+			" keeps writers from being starved by readers, is created next to it, and for commands run by lock"+
+			" \"<file>.lock.held\" and \"<file>.lock.nested\". Defaults to the "+
+			lock.SidecarEnv+" environment variable.",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&lockTimeoutVal,
+		lockTimeoutFlag,
+		0,
+		"Maximum time to wait for a file lock, e.g. 5s. Zero waits without a limit. Defaults to the "+
+			lock.TimeoutEnv+" environment variable.",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&noWaitVal,
+		noWaitFlag,
+		false,
+		"Fail right away when a file is locked by another process.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&fileFormatVal,
+		fileFormatFlag,
+		"",
+		"Format of the files: "+strings.Join(parser.Formats(), ", ")+". Defaults to the format of the extension"+
+			" of each file: .properties, .ini, .json, .yaml or .yml, and env for any other.",
+	)
 }
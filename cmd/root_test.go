@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/kvf"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExitCodes(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	invalidFile, err := createRandomTestFileWithContent("key\n")
+	assert.NoError(t, err)
+	defer removeTestFile(invalidFile.Name())
+
+	testCases := []struct {
+		name         string
+		newCmd       func() *cobra.Command
+		args         []string
+		expectedCode int
+	}{
+		{
+			name:         "success",
+			newCmd:       newGetCmd,
+			args:         []string{tmpFile.Name(), "key"},
+			expectedCode: exitOK,
+		},
+		{
+			name:         "key not found",
+			newCmd:       newGetCmd,
+			args:         []string{tmpFile.Name(), "bogus-key"},
+			expectedCode: exitNotFound,
+		},
+		{
+			name:         "key not found on delete",
+			newCmd:       newDeleteCmd,
+			args:         []string{tmpFile.Name(), "bogus-key", "--must-exist"},
+			expectedCode: exitNotFound,
+		},
+		{
+			name:         "not enough arguments",
+			newCmd:       newGetCmd,
+			args:         []string{tmpFile.Name()},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "empty key",
+			newCmd:       newSetCmd,
+			args:         []string{tmpFile.Name(), "", "value"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "missing file",
+			newCmd:       newGetCmd,
+			args:         []string{"non-existing-file", "key"},
+			expectedCode: exitFailure,
+		},
+		{
+			name:         "invalid file",
+			newCmd:       newGetCmd,
+			args:         []string{invalidFile.Name(), "key"},
+			expectedCode: exitFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := tc.newCmd()
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			err := cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+		})
+	}
+}
+
+func TestExitCodes_FromTypedErrors(t *testing.T) {
+	assert.Equal(t, exitLockTimeout, exitCode(fmt.Errorf("set: %w", lock.ErrTimeout)))
+	assert.Equal(t, exitUsage, exitCode(newUsageError("invalid flag")))
+	assert.Equal(t, exitFailure, exitCode(errors.New("something went wrong")))
+}
+
+func TestUsage_OnlyForUsageErrors(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		usage        bool
+	}{
+		{name: "not enough arguments", args: []string{"get", tmpFile.Name()}, expectedCode: exitUsage, usage: true},
+		{name: "unknown flag", args: []string{"get", "--bogus"}, expectedCode: exitUsage, usage: true},
+		{name: "key not found", args: []string{"get", tmpFile.Name(), "bogus-key"}, expectedCode: exitNotFound},
+		{name: "missing file", args: []string{"get", "non-existing-file", "key"}, expectedCode: exitFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := kvfCommand(t, tc.args...)
+			errBuff := bytes.NewBufferString("")
+			c := exec.Command(args[0], args[1:]...)
+			c.Stderr = errBuff
+
+			err := c.Run()
+			var exitErr *exec.ExitError
+			assert.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tc.expectedCode, exitErr.ExitCode())
+			assert.Contains(t, errBuff.String(), "Error: ")
+			assert.Equal(t, tc.usage, strings.Contains(errBuff.String(), "Usage:"))
+		})
+	}
+}
+
+func TestLockDir_FromEnvironment(t *testing.T) {
+	lockDir := filepath.Join(t.TempDir(), "locks")
+	t.Setenv(lock.DirEnv, lockDir)
+
+	filePath := getRandomTestFilePath()
+	defer removeTestFile(filePath)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{filePath, "key", "value"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(lockDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".lock"))
+	assert.True(t, strings.HasSuffix(entries[1].Name(), ".lock.gate"))
+}
+
+func TestLockDir_FromFlag(t *testing.T) {
+	lockDir := filepath.Join(t.TempDir(), "locks")
+	t.Setenv(lock.DirEnv, filepath.Join(t.TempDir(), "ignored"))
+
+	filePath := getRandomTestFilePath()
+	defer removeTestFile(filePath)
+
+	err := executeRootCmd(t, io.Discard, "set", filePath, "key", "value", "--lock-dir", lockDir)
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(lockDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestLockDir_PrivateDirNotOwned(t *testing.T) {
+	probe, err := os.CreateTemp("/var/lock/kvf", ".probe-*")
+	if err == nil {
+		_ = probe.Close()
+		_ = os.Remove(probe.Name())
+		t.Skip("the system lock directory is used before the private ones")
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv(lock.DirEnv, "")
+
+	// Neither a directory writable by others nor a symlink is taken for the
+	// private lock directory.
+	testCases := []struct {
+		name  string
+		setUp func(t *testing.T, dir string) string
+	}{
+		{name: "writable by others", setUp: func(t *testing.T, dir string) string {
+			assert.NoError(t, os.Mkdir(dir, 0700))
+			assert.NoError(t, os.Chmod(dir, 0777))
+			return dir
+		}},
+		{name: "symlink", setUp: func(t *testing.T, dir string) string {
+			target := t.TempDir()
+			assert.NoError(t, os.Symlink(target, dir))
+			return target
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lockDir := filepath.Join(runtimeDir, "kvf")
+			defer func() { _ = os.RemoveAll(lockDir) }()
+			watched := tc.setUp(t, lockDir)
+
+			filePath := getRandomTestFilePath()
+			defer removeTestFile(filePath)
+			err := executeRootCmd(t, io.Discard, "set", filePath, "key", "value")
+			assert.NoError(t, err)
+
+			entries, err := os.ReadDir(watched)
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestLockDir_NotUsable(t *testing.T) {
+	notADir, err := createRandomTestFileWithContent("")
+	assert.NoError(t, err)
+	defer removeTestFile(notADir.Name())
+	t.Setenv(lock.DirEnv, notADir.Name())
+
+	cmd, _, errBuff := setUpTestSetCmd()
+	cmd.SetArgs([]string{getRandomTestFilePath(), "key", "value"})
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, errBuff.String(), "lock directory")
+}
+
+func TestLockSidecar(t *testing.T) {
+	t.Setenv(lock.SidecarEnv, "1")
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.kvf")
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{filePath, "key", "value"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filePath + ".lock")
+	assert.NoError(t, err)
+	assertFileContentEquals(t, filePath, "key=value\n")
+}
+
+func TestLockTimeout(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	held, err := lock.New(tmpFile.Name())
+	assert.NoError(t, err)
+	defer func() {
+		_ = held.Release()
+	}()
+
+	testCases := []struct {
+		name       string
+		flags      []string
+		minElapsed time.Duration
+	}{
+		{name: "timeout", flags: []string{"--lock-timeout", "200ms"}, minElapsed: 200 * time.Millisecond},
+		{name: "no wait", flags: []string{"--no-wait"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errBuff := bytes.NewBufferString("")
+
+			start := time.Now()
+			err := executeRootCmd(t, errBuff, append([]string{"get", tmpFile.Name(), "key"}, tc.flags...)...)
+			elapsed := time.Since(start)
+
+			assert.Error(t, err)
+			assert.Equal(t, exitLockTimeout, exitCode(err))
+			assert.Contains(t, errBuff.String(), "timed out waiting for lock")
+			assert.GreaterOrEqual(t, elapsed, tc.minElapsed)
+			assert.Less(t, elapsed, tc.minElapsed+time.Second)
+		})
+	}
+}
+
+func TestLockTimeout_FromEnvironment(t *testing.T) {
+	t.Setenv(lock.TimeoutEnv, "100ms")
+
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	held, err := lock.New(tmpFile.Name())
+	assert.NoError(t, err)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "key", "new"})
+	err = cmd.Execute()
+	assert.Equal(t, exitLockTimeout, exitCode(err))
+	assertFileContentEquals(t, tmpFile.Name(), "key=value\n")
+
+	err = held.Release()
+	assert.NoError(t, err)
+
+	cmd, _, _ = setUpTestSetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "key", "new"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assertFileContentEquals(t, tmpFile.Name(), "key=new\n")
+}
+
+func TestSharedLock(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	held, err := lock.NewShared(tmpFile.Name())
+	assert.NoError(t, err)
+	defer func() {
+		_ = held.Release()
+	}()
+
+	err = executeRootCmd(t, io.Discard, "get", tmpFile.Name(), "key", "--no-wait")
+	assert.NoError(t, err, "readers should not block each other")
+
+	err = executeRootCmd(t, io.Discard, "set", tmpFile.Name(), "key", "new", "--no-wait")
+	assert.Equal(t, exitLockTimeout, exitCode(err), "a writer should wait for the readers")
+	assertFileContentEquals(t, tmpFile.Name(), "key=value\n")
+}
+
+func TestSharedLock_ParallelReadersNoWait(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	// Readers passing the gate at the same time must not fail each other.
+	get := strings.Join(kvfCommand(t, "get", tmpFile.Name(), "key", "--no-wait"), " ")
+	script := "for i in $(seq 20); do (for j in $(seq 20); do " + get + " > /dev/null 2>&1 || echo failed; done) & done; wait"
+	out, err := exec.Command("sh", "-c", script).Output()
+	assert.NoError(t, err)
+	assert.Empty(t, string(out))
+}
+
+func TestSharedLock_WriterNotStarved(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=value\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	reader, err := lock.NewShared(tmpFile.Name())
+	assert.NoError(t, err)
+
+	writerDone := make(chan error)
+	go func() {
+		writer, err := lock.New(tmpFile.Name())
+		if err == nil {
+			err = writer.Release()
+		}
+		writerDone <- err
+	}()
+
+	// Give the writer time to start waiting for the reader.
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = lock.NewSharedContext(ctx, tmpFile.Name())
+	assert.ErrorIs(t, err, lock.ErrTimeout, "a new reader should queue up behind the waiting writer")
+
+	err = reader.Release()
+	assert.NoError(t, err)
+	assert.NoError(t, <-writerDone)
+}
+
+// executeRootCmd runs the command through the root command, so that the global
+// flags apply. The global flags, the lock configuration and the file format are
+// reset when the test finishes.
+func executeRootCmd(t *testing.T, errOut io.Writer, args ...string) error {
+	t.Cleanup(func() {
+		rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+			_ = f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+		lock.ResetConfig()
+		kvf.SetFormat(nil)
+	})
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(errOut)
+	rootCmd.SetArgs(args)
+
+	return rootCmd.Execute()
+}
@@ -1,42 +1,282 @@
 package cmd
 
 import (
-	"fmt"
+	"bufio"
+	"errors"
 	"github.com/oxio/kvf/internal/kvf"
 	"github.com/oxio/kvf/internal/parser"
 	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
 )
 
 func newSetCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "set <file1> [<file2> <file3> ...] <key> <value>",
-		Short: "Sets a value to a key-value file",
+	var export *bool
+	var ifAbsent *bool
+	var ifPresent *bool
+	var ifValue *string
+	var ifVersion *string
+	var valueFile *string
+	var keepNewline *bool
+	var batch *bool
+
+	cmd := &cobra.Command{
+		Use: "set <file1> [<file2> <file3> ...] <key> <value> | <file1> [<file2> ...] -- <key1>=<value1> [<key2>=<value2> ...]" +
+			" | <file1> [<file2> ...] <key> --value-file <path> | <file1> [<file2> ...] --batch",
+		Short: "Sets values to the key-value file(s)",
+		Long: "Sets values to the key-value file(s). All keys of all files are set in a single transaction: either" +
+			" every file is updated or none of them is. With a condition flag the files are updated only if the" +
+			" condition holds for all of them, otherwise the command exits with code 5. A value of \"-\" is read from" +
+			" stdin, dropping a single trailing line break unless --" + keepNewlineFlag + " is given.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			files, items, err := parseSetArgs(cmd, args, *valueFile, *keepNewline, *batch)
+			if err != nil {
+				return err
+			}
+
+			for _, item := range items {
+				item.Export = item.Export || *export
+			}
 
-			if len(args) < 3 {
-				return fmt.Errorf("not enough arguments")
+			repos := make([]*kvf.RepoImpl, 0, len(files))
+			for _, file := range uniqueFiles(files) {
+				repos = append(repos, kvf.NewRepo(file, false))
 			}
 
-			files := args[:len(args)-2]
-			key := args[len(args)-2]
-			value := args[len(args)-1]
+			keys := make([]string, 0, len(items))
+			for _, item := range items {
+				keys = append(keys, item.Key)
+			}
 
-			for _, file := range files {
-				repo := kvf.NewRepo(file, false)
-				item, err := parser.NewItem(key, value)
-				if err != nil {
-					return err
+			var condition kvf.Condition
+			switch {
+			case *ifAbsent:
+				condition = kvf.IfAbsent(keys...)
+			case *ifPresent:
+				condition = kvf.IfPresent(keys...)
+			case cmd.Flag(ifValueFlag).Changed:
+				if len(items) != 1 {
+					return newUsageError("--%s requires a single key", ifValueFlag)
 				}
-
-				err = repo.Set(item)
-				if err != nil {
-					return err
+				condition = kvf.IfValue(items[0].Key, *ifValue)
+			case cmd.Flag(ifVersionFlag).Changed:
+				if len(repos) != 1 {
+					return newUsageError("--%s requires a single file", ifVersionFlag)
 				}
+				condition = kvf.IfVersion(*ifVersion)
 			}
 
-			return nil
+			err = kvf.SetAllIf(repos, condition, items...)
+			if errors.Is(err, parser.ErrInvalidKey) {
+				// The key can't be written in the format of one of the files.
+				return &usageError{err: err}
+			}
+			return err
 		},
 	}
+
+	export = cmd.Flags().BoolP(
+		exportFlag,
+		exportShortFlag,
+		false,
+		"Prefix newly written keys with \"export\", so the file can be sourced by a shell. Existing lines keep their"+
+			" prefix as it is.",
+	)
+	ifAbsent = cmd.Flags().Bool(
+		ifAbsentFlag,
+		false,
+		"Set the keys only if none of them is set yet.",
+	)
+	ifPresent = cmd.Flags().Bool(
+		ifPresentFlag,
+		false,
+		"Set the keys only if all of them are set already.",
+	)
+	ifValue = cmd.Flags().String(
+		ifValueFlag,
+		"",
+		"Set the key only if its current value is the given one.",
+	)
+	ifVersion = cmd.Flags().String(
+		ifVersionFlag,
+		"",
+		"Set the keys only if the file has not changed since \"get --"+printVersionFlag+"\" returned the given"+
+			" version.",
+	)
+	valueFile = cmd.Flags().String(
+		valueFileFlag,
+		"",
+		"Read the value from the file instead of the last argument, \"-\" reads it from stdin. A single trailing"+
+			" line break is dropped.",
+	)
+	keepNewline = cmd.Flags().Bool(
+		keepNewlineFlag,
+		false,
+		"Keep the value read from stdin or a file exactly as it is, including a trailing line break.",
+	)
+	batch = cmd.Flags().BoolP(
+		batchFlag,
+		batchShortFlag,
+		false,
+		"Read key=value lines from stdin and set them all in a single transaction. The input has the syntax of"+
+			" the files, so values can be quoted and comments are skipped.",
+	)
+	cmd.MarkFlagsMutuallyExclusive(valueFileFlag, batchFlag)
+	cmd.MarkFlagsMutuallyExclusive(keepNewlineFlag, batchFlag)
+	cmd.MarkFlagsMutuallyExclusive(ifAbsentFlag, ifPresentFlag, ifValueFlag, ifVersionFlag)
+
+	return cmd
+}
+
+func parseSetArgs(
+	cmd *cobra.Command,
+	args []string,
+	valueFile string,
+	keepNewline bool,
+	batch bool,
+) (files []string, items []*parser.Item, err error) {
+	dash := cmd.ArgsLenAtDash()
+
+	if batch {
+		if dash >= 0 || len(args) == 0 {
+			return nil, nil, newUsageError("--%s expects only files as arguments", batchFlag)
+		}
+		items, err = readBatch(cmd.InOrStdin())
+		if err != nil {
+			return nil, nil, err
+		}
+		return args, items, nil
+	}
+
+	if dash >= 0 {
+		if valueFile != "" {
+			return nil, nil, newUsageError("--%s can't be used with key-value pairs", valueFileFlag)
+		}
+		files, pairs := args[:dash], args[dash:]
+		if len(files) == 0 || len(pairs) == 0 {
+			return nil, nil, newUsageError("not enough arguments")
+		}
+		for _, pair := range pairs {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, nil, newUsageError("invalid key-value pair, expected <key>=<value>: %s", pair)
+			}
+			item, err := parser.NewItem(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return files, items, nil
+	}
+
+	var value string
+	if valueFile != "" {
+		if len(args) < 2 {
+			return nil, nil, newUsageError("not enough arguments")
+		}
+		value, err = readValueFile(cmd, valueFile, keepNewline)
+		if err != nil {
+			return nil, nil, err
+		}
+		files, args = args[:len(args)-1], args[len(args)-1:]
+	} else {
+		if len(args) < 3 {
+			return nil, nil, newUsageError("not enough arguments")
+		}
+		value = args[len(args)-1]
+		if value == stdinArg {
+			value, err = readValue(cmd.InOrStdin(), keepNewline)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		files, args = args[:len(args)-2], args[len(args)-2:len(args)-1]
+	}
+
+	// A key-value pair in place of the key is taken for an attempt to set
+	// several pairs, which would otherwise be written as the value.
+	if strings.Contains(args[0], "=") {
+		return nil, nil, newUsageError("invalid key: %s, use -- to set several key-value pairs", args[0])
+	}
+	item, err := parser.NewItem(args[0], value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return files, []*parser.Item{item}, nil
+}
+
+// readValueFile reads the value from the file, or from stdin for "-".
+func readValueFile(cmd *cobra.Command, valueFile string, keepNewline bool) (string, error) {
+	if valueFile == stdinArg {
+		return readValue(cmd.InOrStdin(), keepNewline)
+	}
+
+	fp, err := os.Open(valueFile)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = fp.Close()
+	}()
+
+	return readValue(fp, keepNewline)
+}
+
+// readValue reads the whole input as a value. A single trailing line break is
+// dropped, as one is added by most tools writing a value, e.g. echo, unless
+// the value is kept as it is.
+func readValue(r io.Reader, keepNewline bool) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	value := string(content)
+	if keepNewline {
+		return value, nil
+	}
+	if trimmed, ok := strings.CutSuffix(value, "\n"); ok {
+		value = strings.TrimSuffix(trimmed, "\r")
+	}
+	return value, nil
+}
+
+// readBatch reads key-value pairs from the input. The input has the syntax of
+// the key-value files, so values can be quoted and span several lines, while
+// comments and empty lines are skipped.
+func readBatch(r io.Reader) ([]*parser.Item, error) {
+	var items []*parser.Item
+	p := parser.NewLineParser()
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line != "" {
+			parsed, parseErr := p.Parse(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			if parsed != nil && !parsed.IsEmpty && !parsed.IsComment {
+				// The items are made anew, so they are quoted as set would do.
+				item, newErr := parser.NewItem(parsed.Key, parsed.Val)
+				if newErr != nil {
+					return nil, newErr
+				}
+				item.Export = parsed.Export
+				items = append(items, item)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return items, p.Close()
 }
 
 func init() {
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -95,6 +98,139 @@ func TestSetMultipleFiles_Success(t *testing.T) {
 	assertFileContentEquals(t, filePath2, "foo=bar\n")
 }
 
+func TestSetMultipleKeysInMultipleFiles_Success(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key1=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("# comment\nkey2=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), testFile1.Name(), "--", "key1=a", "key2=b=c", "key3="})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	assertFileContentEquals(t, testFile1.Name(), "key1=a\nkey2=b=c\nkey3=\n")
+	assertFileContentEquals(t, testFile2.Name(), "# comment\nkey2=b=c\nkey1=a\nkey3=\n")
+}
+
+func TestSetMultipleFiles_NoFileChangesWhenOneFails(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("invalid line\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, errBuff := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "--", "key=new", "other=new"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, errBuff.String(), "invalid line: invalid line")
+
+	assertFileContentEquals(t, testFile1.Name(), "key=old\n")
+	assertFileContentEquals(t, testFile2.Name(), "invalid line\n")
+}
+
+func TestSetMultipleFiles_NoFileCreatedWhenOneFails(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("invalid line\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+	missingFile := getRandomTestFilePath()
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{missingFile, testFile.Name(), "key", "new"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assert.NoFileExists(t, missingFile)
+	assertFileContentEquals(t, testFile.Name(), "invalid line\n")
+}
+
+func TestSetMultipleFiles_RollbackWhenWriteFails(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("key=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+	missingFile := getRandomTestFilePath()
+	unwritableFile := filepath.Join(getRandomTestFilePath(), "missing-dir.env")
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile.Name(), missingFile, unwritableFile, "key", "new"})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+	assertFileContentEquals(t, testFile.Name(), "key=old\n")
+	assert.NoFileExists(t, missingFile)
+}
+
+func TestSetSeveralPairsWithoutDashes(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("K1=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("K2=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, errBuff := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "K1=V1", "K2=V2"})
+
+	err = cmd.Execute()
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, errBuff.String(), "invalid key: K1=V1, use -- to set several key-value pairs")
+	assertFileContentEquals(t, testFile1.Name(), "K1=old\n")
+	assertFileContentEquals(t, testFile2.Name(), "K2=old\n")
+}
+
+func TestSetInvalidKey(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "comment", args: []string{"#key", "value"}},
+		{name: "blank", args: []string{"my key", "value"}},
+		{name: "line break", args: []string{"my\nkey", "value"}},
+		{name: "blank in pair", args: []string{"--", "my key=value"}},
+		{name: "comment in pair", args: []string{"--", "#key=value"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testFile, err := createRandomTestFileWithContent("key=value\n")
+			assert.NoError(t, err)
+			defer removeTestFile(testFile.Name())
+
+			cmd, _, errBuff := setUpTestSetCmd()
+			cmd.SetArgs(append([]string{testFile.Name()}, tc.args...))
+
+			err = cmd.Execute()
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, errBuff.String(), "invalid key: ")
+			assertFileContentEquals(t, testFile.Name(), "key=value\n")
+		})
+	}
+}
+
+func TestSetInvalidKeyValuePair(t *testing.T) {
+	filePath := getRandomTestFilePath()
+
+	cmd, _, errBuff := setUpTestSetCmd()
+	cmd.SetArgs([]string{filePath, "--", "key=value", "no-separator"})
+
+	err := cmd.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, errBuff.String(), "invalid key-value pair, expected <key>=<value>: no-separator")
+
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestMultipleConcurrentWrites_Success(t *testing.T) {
 	filePath := getRandomTestFilePath()
 	defer removeTestFile(filePath)
@@ -123,6 +259,107 @@ func TestMultipleConcurrentWrites_Success(t *testing.T) {
 	}
 }
 
+func TestMultipleConcurrentMultiFileWrites_Success(t *testing.T) {
+	filePath1 := getRandomTestFilePath()
+	defer removeTestFile(filePath1)
+	filePath2 := getRandomTestFilePath()
+	defer removeTestFile(filePath2)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 200; i++ {
+		cmd, _, _ := setUpTestSetCmd()
+		commandId := strconv.Itoa(i)
+		if i%2 == 0 {
+			cmd.SetArgs([]string{filePath1, filePath2, "--", "key" + commandId + "=value", "last=" + commandId})
+		} else {
+			cmd.SetArgs([]string{filePath2, filePath1, "--", "key" + commandId + "=value", "last=" + commandId})
+		}
+		wg.Add(1)
+		go func(cmd *cobra.Command) {
+			defer wg.Done()
+			err := cmd.Execute()
+			assert.NoError(t, err)
+		}(cmd)
+	}
+
+	wg.Wait()
+
+	content1, err := getTestFileContents(filePath1)
+	assert.NoError(t, err)
+	content2, err := getTestFileContents(filePath2)
+	assert.NoError(t, err)
+	assert.Contains(t, *content1, "key199=value")
+	assert.Regexp(t, "last=[0-9]+\n", *content1)
+
+	lastLine := func(content string) string {
+		for _, line := range strings.Split(content, "\n") {
+			if strings.HasPrefix(line, "last=") {
+				return line
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, lastLine(*content1), lastLine(*content2))
+}
+
+func TestMultipleConcurrentWritesThroughDifferentPaths_Success(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.kvf")
+	linkPath := filepath.Join(dir, "link.kvf")
+	err := os.Symlink("file.kvf", linkPath)
+	assert.NoError(t, err)
+
+	workDir, err := os.Getwd()
+	assert.NoError(t, err)
+	err = os.Chdir(dir)
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Chdir(workDir)
+	}()
+
+	paths := []string{filePath, linkPath, "file.kvf", "./link.kvf", filepath.Join(dir, ".", "file.kvf")}
+
+	var wg sync.WaitGroup
+
+	var commandIds []string
+	for i := 0; i < 500; i++ {
+		cmd, _, _ := setUpTestSetCmd()
+		commandId := strconv.Itoa(i)
+		commandIds = append(commandIds, commandId)
+		cmd.SetArgs([]string{paths[i%len(paths)], "key" + commandId, "value" + commandId})
+		wg.Add(1)
+		go func(cmd *cobra.Command) {
+			defer wg.Done()
+			err := cmd.Execute()
+			assert.NoError(t, err)
+		}(cmd)
+	}
+
+	wg.Wait()
+
+	for _, commandId := range commandIds {
+		assertFileContentContains(t, filePath, "key"+commandId+"=value"+commandId)
+	}
+}
+
+func TestSetSameFileThroughDifferentPaths(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.kvf")
+	linkPath := filepath.Join(dir, "link.kvf")
+	err := os.WriteFile(filePath, []byte(""), 0644)
+	assert.NoError(t, err)
+	err = os.Symlink(filePath, linkPath)
+	assert.NoError(t, err)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{filePath, linkPath, "--", "key1=value1", "key2=value2"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	assertFileContentEquals(t, filePath, "key1=value1\nkey2=value2\n")
+}
+
 func TestMultipleSetsOfTheSameKeyCausingNewLinesToBeAdded_Regression(t *testing.T) {
 	testFile, err := createRandomTestFileWithContent("key=\"foo\"\n")
 	assert.NoError(t, err)
@@ -208,6 +445,571 @@ func TestPreserveNewLinesCount(t *testing.T) {
 	}
 }
 
+func TestSetPreservesFormatting(t *testing.T) {
+	testCases := []struct {
+		name            string
+		originalContent string
+		key             string
+		newValue        string
+		expectedContent string
+	}{
+		{
+			name:            "spaces around separator",
+			originalContent: "key1 = value1\nkey2 = value2\n",
+			key:             "key2",
+			newValue:        "new",
+			expectedContent: "key1 = value1\nkey2 = new\n",
+		},
+		{
+			name:            "comments without space after hash",
+			originalContent: "#comment\n##  another comment\nkey=value\n",
+			key:             "key",
+			newValue:        "new",
+			expectedContent: "#comment\n##  another comment\nkey=new\n",
+		},
+		{
+			name:            "indentation and trailing whitespace",
+			originalContent: "  key1=value1  \n\tkey2 =  'value2'\t\n   \n",
+			key:             "key2",
+			newValue:        "new",
+			expectedContent: "  key1=value1  \n\tkey2 =  'new'\t\n   \n",
+		},
+		{
+			name:            "empty value",
+			originalContent: "key1=\nkey2 =   \n",
+			key:             "key2",
+			newValue:        "new",
+			expectedContent: "key1=\nkey2 =new   \n",
+		},
+		{
+			name:            "CRLF line endings",
+			originalContent: "# comment\r\nkey1=value1\r\n\r\nkey2=value2\r\n",
+			key:             "key1",
+			newValue:        "new",
+			expectedContent: "# comment\r\nkey1=new\r\n\r\nkey2=value2\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testFile, err := createRandomTestFileWithContent(tc.originalContent)
+			assert.NoError(t, err)
+			defer removeTestFile(testFile.Name())
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SetArgs([]string{testFile.Name(), tc.key, tc.newValue})
+
+			err = cmd.Execute()
+			assert.NoError(t, err)
+
+			assertFileContentEquals(t, testFile.Name(), tc.expectedContent)
+		})
+	}
+}
+
+func TestSetMultiLineValue(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("key1=value1\nkey2=\"old\nvalue\"\nkey3=value3\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile.Name(), "key1", "first\nsecond"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	cmd, _, _ = setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile.Name(), "key2", "new\nmulti\nline"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	cmd, _, _ = setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile.Name(), "key4", "a\nb"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	assertFileContentEquals(
+		t,
+		testFile.Name(),
+		"key1=\"first\nsecond\"\nkey2=\"new\nmulti\nline\"\nkey3=value3\nkey4=\"a\nb\"\n",
+	)
+
+	getCmd := newGetCmd()
+	outBuff := bytes.NewBufferString("")
+	getCmd.SetOut(outBuff)
+	getCmd.SetArgs([]string{testFile.Name(), "key2"})
+	err = getCmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "new\nmulti\nline", outBuff.String())
+}
+
+func TestSetAndGetRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name            string
+		value           string
+		expectedContent string
+	}{
+		{name: "plain", value: "plain value", expectedContent: "key=plain value\n"},
+		{name: "empty", value: "", expectedContent: "key=\n"},
+		{name: "leading and trailing spaces", value: "  padded ", expectedContent: "key='  padded '\n"},
+		{name: "double quotes", value: `"x"`, expectedContent: "key='\"x\"'\n"},
+		{name: "single quotes", value: "'x'", expectedContent: "key=\"'x'\"\n"},
+		{name: "hash", value: "foo #bar", expectedContent: "key='foo #bar'\n"},
+		{name: "backslashes", value: `C:\dir\n`, expectedContent: "key=C:\\dir\\n\n"},
+		{name: "new line", value: "a\nb", expectedContent: "key=\"a\nb\"\n"},
+		{name: "dollar", value: "${HOME}", expectedContent: "key='${HOME}'\n"},
+		{name: "dollar and quote", value: "it's $5", expectedContent: "key=\"it's $5\"\n"},
+		{name: "reference and quote", value: "it's ${HOME}", expectedContent: "key=\"it's \\${HOME}\"\n"},
+		{name: "tab", value: "\ta", expectedContent: "key='\ta'\n"},
+		{name: "carriage return", value: "a\r\n", expectedContent: "key=\"a\\r\n\"\n"},
+		{
+			name:            "everything",
+			value:           " it's \"quoted\" \\n\n",
+			expectedContent: "key=\" it's \\\"quoted\\\" \\\\n\n\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := getRandomTestFilePath()
+			defer removeTestFile(filePath)
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SetArgs([]string{filePath, "key", tc.value})
+			err := cmd.Execute()
+			assert.NoError(t, err)
+
+			assertFileContentEquals(t, filePath, tc.expectedContent)
+
+			getCmd := newGetCmd()
+			outBuff := bytes.NewBufferString("")
+			getCmd.SetOut(outBuff)
+			getCmd.SetArgs([]string{filePath, "key"})
+			err = getCmd.Execute()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.value, outBuff.String())
+		})
+	}
+}
+
+func TestSetKeepsQuotesWhenPossible(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("key1='foo'\nkey2='foo'\nkey3=\"foo\"\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	args := [][]string{
+		{testFile.Name(), "key1", "bar"},
+		{testFile.Name(), "key2", "it's"},
+		{testFile.Name(), "key3", "bar"},
+	}
+	for _, a := range args {
+		cmd, _, _ := setUpTestSetCmd()
+		cmd.SetArgs(a)
+		err = cmd.Execute()
+		assert.NoError(t, err)
+	}
+
+	assertFileContentEquals(t, testFile.Name(), "key1='bar'\nkey2=\"it's\"\nkey3=\"bar\"\n")
+}
+
+func TestSetKeepsExportPrefixAndInlineComments(t *testing.T) {
+	originalContent := `export key1=value1 # first note
+key2="value2"   # second note
+export   key3='value3'
+key5= # fifth note
+`
+	expectedContent := `export key1=new1 # first note
+key2="new2"   # second note
+export   key3='new3'
+key5=new5 # fifth note
+export key4=new4
+`
+	testFile, err := createRandomTestFileWithContent(originalContent)
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	args := [][]string{
+		{testFile.Name(), "key1", "new1"},
+		{testFile.Name(), "key2", "new2"},
+		{testFile.Name(), "key3", "new3"},
+		{testFile.Name(), "key5", "new5"},
+		{testFile.Name(), "key4", "new4", "--export"},
+	}
+	for _, a := range args {
+		cmd, _, _ := setUpTestSetCmd()
+		cmd.SetArgs(a)
+		err = cmd.Execute()
+		assert.NoError(t, err)
+	}
+
+	assertFileContentEquals(t, testFile.Name(), expectedContent)
+}
+
+func TestSetKeepsFileMode(t *testing.T) {
+	testFile, err := createRandomTestFileWithContent("key=foo\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile.Name())
+
+	err = os.Chmod(testFile.Name(), 0600)
+	assert.NoError(t, err)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile.Name(), "key", "bar"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	info, err := os.Stat(testFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assertFileContentEquals(t, testFile.Name(), "key=bar\n")
+}
+
+func TestSetThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.kvf")
+	link := filepath.Join(dir, "link.kvf")
+
+	err := os.WriteFile(target, []byte("key=foo\n"), 0644)
+	assert.NoError(t, err)
+	err = os.Symlink(target, link)
+	assert.NoError(t, err)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{link, "key", "bar"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	info, err := os.Lstat(link)
+	assert.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	assertFileContentEquals(t, target, "key=bar\n")
+}
+
+func TestSetLeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.kvf")
+
+	for i := 0; i < 3; i++ {
+		cmd, _, _ := setUpTestSetCmd()
+		cmd.SetArgs([]string{filePath, "key" + strconv.Itoa(i), "value"})
+		err := cmd.Execute()
+		assert.NoError(t, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assertFileContentEquals(t, filePath, "key0=value\nkey1=value\nkey2=value\n")
+}
+
+func TestSetConditional(t *testing.T) {
+	testCases := []struct {
+		name            string
+		args            []string
+		expectedContent string
+		expectedCode    int
+	}{
+		{
+			name:            "if absent, key missing",
+			args:            []string{"new", "value", "--if-absent"},
+			expectedContent: "key=old\nnew=value\n",
+		},
+		{
+			name:            "if absent, key set",
+			args:            []string{"key", "value", "--if-absent"},
+			expectedContent: "key=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "if absent, one of the keys set",
+			args:            []string{"--if-absent", "--", "new=value", "key=value"},
+			expectedContent: "key=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "if present, key set",
+			args:            []string{"key", "value", "--if-present"},
+			expectedContent: "key=value\n",
+		},
+		{
+			name:            "if present, key missing",
+			args:            []string{"new", "value", "--if-present"},
+			expectedContent: "key=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "if value matches",
+			args:            []string{"key", "value", "--if-value", "old"},
+			expectedContent: "key=value\n",
+		},
+		{
+			name:            "if value differs",
+			args:            []string{"key", "value", "--if-value", "other"},
+			expectedContent: "key=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "if value, key missing",
+			args:            []string{"new", "value", "--if-value", ""},
+			expectedContent: "key=old\n",
+			expectedCode:    exitCondition,
+		},
+		{
+			name:            "if value with multiple keys",
+			args:            []string{"--if-value", "old", "--", "key=value", "new=value"},
+			expectedContent: "key=old\n",
+			expectedCode:    exitUsage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := createRandomTestFileWithContent("key=old\n")
+			assert.NoError(t, err)
+			defer removeTestFile(tmpFile.Name())
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SetArgs(append([]string{tmpFile.Name()}, tc.args...))
+			err = cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			assertFileContentEquals(t, tmpFile.Name(), tc.expectedContent)
+		})
+	}
+}
+
+func TestSetConditional_MultipleFiles(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("key=other\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, errBuff := setUpTestSetCmd()
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "key", "new", "--if-value", "old"})
+	err = cmd.Execute()
+	assert.Equal(t, exitCondition, exitCode(err))
+	assert.Contains(t, errBuff.String(), "condition failed: unexpected value of key: key")
+
+	assertFileContentEquals(t, testFile1.Name(), "key=old\n")
+	assertFileContentEquals(t, testFile2.Name(), "key=other\n")
+}
+
+func TestSetConditional_MissingFile(t *testing.T) {
+	testCases := [][]string{
+		{"key", "value", "--if-present"},
+		{"key", "value", "--if-value", "old"},
+		{"key", "value", "--if-version", "0000000000000000"},
+	}
+
+	for _, args := range testCases {
+		filePath := getRandomTestFilePath()
+		cmd, _, _ := setUpTestSetCmd()
+		cmd.SetArgs(append([]string{filePath}, args...))
+		err := cmd.Execute()
+		assert.Equal(t, exitCondition, exitCode(err), args)
+		assert.NoFileExists(t, filePath, args)
+	}
+}
+
+func TestSetIfVersion(t *testing.T) {
+	tmpFile, err := createRandomTestFileWithContent("key=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(tmpFile.Name())
+
+	getCmd, outBuff, _ := setUpTestGetCmd()
+	getCmd.SetArgs([]string{tmpFile.Name(), "key", "--print-version"})
+	err = getCmd.Execute()
+	assert.NoError(t, err)
+	version, value, _ := strings.Cut(outBuff.String(), "\n")
+	assert.Equal(t, "old", value)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "key", "new", "--if-version", version})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assertFileContentEquals(t, tmpFile.Name(), "key=new\n")
+
+	cmd, _, _ = setUpTestSetCmd()
+	cmd.SetArgs([]string{tmpFile.Name(), "key", "newer", "--if-version", version})
+	err = cmd.Execute()
+	assert.Equal(t, exitCondition, exitCode(err), "the version should change with the content")
+	assertFileContentEquals(t, tmpFile.Name(), "key=new\n")
+}
+
+func TestSetIfVersion_Concurrent(t *testing.T) {
+	filePath := getRandomTestFilePath()
+	defer removeTestFile(filePath)
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetArgs([]string{filePath, "counter", "0"})
+	assert.NoError(t, cmd.Execute())
+
+	// Every worker increments the counter with optimistic retries, so no
+	// increment may be lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				for {
+					getCmd, outBuff, _ := setUpTestGetCmd()
+					getCmd.SetArgs([]string{filePath, "counter", "--print-version"})
+					assert.NoError(t, getCmd.Execute())
+					version, value, _ := strings.Cut(outBuff.String(), "\n")
+					counter, err := strconv.Atoi(value)
+					assert.NoError(t, err)
+
+					setCmd, _, _ := setUpTestSetCmd()
+					setCmd.SetArgs([]string{filePath, "counter", strconv.Itoa(counter + 1), "--if-version", version})
+					err = setCmd.Execute()
+					if exitCode(err) != exitCondition {
+						assert.NoError(t, err)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertFileContentEquals(t, filePath, "counter=100\n")
+}
+
+func TestSetValueFromInput(t *testing.T) {
+	valueFile, err := createRandomTestFileWithContent("from file\n")
+	assert.NoError(t, err)
+	defer removeTestFile(valueFile.Name())
+
+	testCases := []struct {
+		name            string
+		args            []string
+		stdin           string
+		expectedContent string
+		expectedCode    int
+	}{
+		{
+			name:            "value from stdin",
+			args:            []string{"key", "-"},
+			stdin:           "secret\n",
+			expectedContent: "key=secret\n",
+		},
+		{
+			name:            "multi-line value from stdin",
+			args:            []string{"key", "-"},
+			stdin:           "line 1\nline 2\n\n",
+			expectedContent: "key=\"line 1\nline 2\n\"\n",
+		},
+		{
+			name:            "value from stdin without line break",
+			args:            []string{"key", "-"},
+			stdin:           "secret",
+			expectedContent: "key=secret\n",
+		},
+		{
+			name:            "value from file",
+			args:            []string{"key", "--value-file", valueFile.Name()},
+			expectedContent: "key=from file\n",
+		},
+		{
+			name:            "value file from stdin",
+			args:            []string{"key", "--value-file", "-"},
+			stdin:           "secret\r\n",
+			expectedContent: "key=secret\n",
+		},
+		{
+			name:            "value from stdin kept as it is",
+			args:            []string{"key", "-", "--keep-newline"},
+			stdin:           "secret\n",
+			expectedContent: "key=\"secret\n\"\n",
+		},
+		{
+			name:            "value file kept as it is",
+			args:            []string{"key", "--value-file", valueFile.Name(), "--keep-newline"},
+			expectedContent: "key=\"from file\n\"\n",
+		},
+		{
+			name:         "missing value file",
+			args:         []string{"key", "--value-file", valueFile.Name() + ".missing"},
+			expectedCode: exitFailure,
+		},
+		{
+			name:         "value file with key-value pairs",
+			args:         []string{"--value-file", valueFile.Name(), "--", "key=value"},
+			expectedCode: exitUsage,
+		},
+		{
+			name: "batch",
+			args: []string{"--batch"},
+			stdin: "# comment\n\nkey1=value1\nexport key2 = \"two words\"\nkey3=\"multi\nline\"\n" +
+				"key4=value # comment\n",
+			expectedContent: "key1=value1\nexport key2=two words\nkey3=\"multi\nline\"\nkey4=value\n",
+		},
+		{
+			name:            "batch without trailing line break",
+			args:            []string{"-b"},
+			stdin:           "key1=value1\r\nkey2=value2",
+			expectedContent: "key1=value1\nkey2=value2\n",
+		},
+		{
+			name:         "batch with invalid line",
+			args:         []string{"--batch"},
+			stdin:        "key1=value1\ninvalid\n",
+			expectedCode: exitFailure,
+		},
+		{
+			name:         "batch with keys",
+			args:         []string{"--batch", "--", "key=value"},
+			expectedCode: exitUsage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.kvf")
+
+			cmd, _, _ := setUpTestSetCmd()
+			cmd.SetIn(strings.NewReader(tc.stdin))
+			cmd.SetArgs(append([]string{filePath}, tc.args...))
+			err := cmd.Execute()
+			assert.Equal(t, tc.expectedCode, exitCode(err))
+			if tc.expectedCode == exitOK {
+				assertFileContentEquals(t, filePath, tc.expectedContent)
+			} else {
+				_, err = os.Stat(filePath)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
+
+func TestSetBatch_MultipleFiles(t *testing.T) {
+	testFile1, err := createRandomTestFileWithContent("key1=old\n")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile1.Name())
+
+	testFile2, err := createRandomTestFileWithContent("")
+	assert.NoError(t, err)
+	defer removeTestFile(testFile2.Name())
+
+	cmd, _, _ := setUpTestSetCmd()
+	cmd.SetIn(strings.NewReader("key1=new\nkey2=new\n"))
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "--batch", "--if-absent"})
+	err = cmd.Execute()
+	assert.Equal(t, exitCondition, exitCode(err))
+	assertFileContentEquals(t, testFile1.Name(), "key1=old\n")
+	assertFileContentEquals(t, testFile2.Name(), "")
+
+	cmd, _, _ = setUpTestSetCmd()
+	cmd.SetIn(strings.NewReader("key1=new\nkey2=new\n"))
+	cmd.SetArgs([]string{testFile1.Name(), testFile2.Name(), "--batch"})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assertFileContentEquals(t, testFile1.Name(), "key1=new\nkey2=new\n")
+	assertFileContentEquals(t, testFile2.Name(), "key1=new\nkey2=new\n")
+}
+
 func setUpTestSetCmd() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
 	cmd := newSetCmd()
 	outBuff := bytes.NewBufferString("")
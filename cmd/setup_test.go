@@ -13,8 +13,19 @@ import (
 
 const (
 	testDir = "kvf-test-dir"
+
+	// runRootCmdEnv makes the test binary run the root command instead of the
+	// tests, so that tests can run it as a child process.
+	runRootCmdEnv = "KVF_TEST_RUN_ROOT_CMD"
 )
 
+func TestMain(m *testing.M) {
+	if os.Getenv(runRootCmdEnv) != "" {
+		Execute()
+	}
+	os.Exit(m.Run())
+}
+
 func getTestFileDir() string {
 	dirPath := filepath.Join(os.TempDir(), testDir)
 	err := os.Mkdir(dirPath, os.ModePerm)
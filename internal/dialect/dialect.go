@@ -0,0 +1,80 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownDialect   = errors.New("unknown dialect")
+	ErrInvalidKey       = errors.New("key is not a valid variable name")
+	ErrUnsupportedValue = errors.New("value can't be represented")
+)
+
+// Dialect renders keys with their values in the syntax of a consumer, e.g. a
+// shell or a configuration file of another tool.
+type Dialect interface {
+	// Render returns the text assigning the value to the key, ending with a
+	// line break.
+	Render(key string, val string) (string, error)
+}
+
+type renderFunc func(key string, val string) (string, error)
+
+func (f renderFunc) Render(key string, val string) (string, error) {
+	return f(key, val)
+}
+
+var dialects = map[string]Dialect{
+	"sh":         renderFunc(renderSh),
+	"fish":       renderFunc(renderFish),
+	"powershell": renderFunc(renderPowerShell),
+	"docker-env": renderFunc(renderDockerEnv),
+	"systemd":    renderFunc(renderSystemd),
+	"github-env": renderFunc(renderGitHubEnv),
+	"make":       renderFunc(renderMake),
+}
+
+// Get returns the dialect of the name.
+func Get(name string) (Dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownDialect, name, strings.Join(Names(), ", "))
+	}
+	return d, nil
+}
+
+// Names returns the names of all dialects in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsShellName reports whether the key can be used as a shell variable name.
+func IsShellName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func requireShellName(key string) error {
+	if !IsShellName(key) {
+		return fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return nil
+}
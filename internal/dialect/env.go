@@ -0,0 +1,66 @@
+package dialect
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// renderDockerEnv renders a line of a file for "docker run --env-file", which
+// takes values literally and has no way to represent line breaks.
+func renderDockerEnv(key string, val string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "#") || strings.ContainsFunc(key, func(c rune) bool {
+		return c == '=' || unicode.IsSpace(c)
+	}) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	if strings.ContainsAny(val, "\r\n") {
+		return "", fmt.Errorf("%w: docker-env values can't span several lines: %s", ErrUnsupportedValue, key)
+	}
+	return key + "=" + val + "\n", nil
+}
+
+var systemdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// renderSystemd renders a line of a file for the EnvironmentFile= setting of
+// systemd units. Line breaks are kept within the double quotes.
+func renderSystemd(key string, val string) (string, error) {
+	err := requireShellName(key)
+	if err != nil {
+		return "", err
+	}
+	return key + `="` + systemdEscaper.Replace(val) + "\"\n", nil
+}
+
+// renderGitHubEnv renders a line of the $GITHUB_ENV file of GitHub Actions.
+// Values spanning several lines are written with the heredoc syntax, using a
+// random delimiter which does not appear in the value.
+func renderGitHubEnv(key string, val string) (string, error) {
+	if key == "" || strings.ContainsAny(key, "=<\r\n") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	if !strings.ContainsAny(val, "\r\n") {
+		return key + "=" + val + "\n", nil
+	}
+
+	for {
+		delimiter, err := randomDelimiter()
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(val, delimiter) {
+			return key + "<<" + delimiter + "\n" + val + "\n" + delimiter + "\n", nil
+		}
+	}
+}
+
+func randomDelimiter() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
+}
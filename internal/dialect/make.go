@@ -0,0 +1,42 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// renderMake renders a variable of GNU make. Values spanning several lines or
+// having a "#" are written in a define block, whose body make takes verbatim.
+// "$" is doubled, so it is not taken for a variable reference, and the empty
+// reference "$()" protects leading whitespace and trailing backslashes, which
+// make would strip or take for a line continuation.
+func renderMake(key string, val string) (string, error) {
+	err := requireShellName(key)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(val, "$", "$$"), "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, `\`) {
+			lines[i] += "$()"
+		}
+	}
+
+	if len(lines) == 1 && !strings.Contains(val, "#") {
+		if strings.TrimLeftFunc(val, unicode.IsSpace) != val {
+			return key + " := $()" + lines[0] + "\n", nil
+		}
+		return key + " := " + lines[0] + "\n", nil
+	}
+
+	for _, line := range lines {
+		directive := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if strings.HasPrefix(directive, "define") || strings.HasPrefix(directive, "endef") {
+			return "", fmt.Errorf("%w: make values can't have lines starting with define or endef: %s",
+				ErrUnsupportedValue, key)
+		}
+	}
+	return "define " + key + "\n" + strings.Join(lines, "\n") + "\nendef\n", nil
+}
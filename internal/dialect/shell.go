@@ -0,0 +1,55 @@
+package dialect
+
+import (
+	"strings"
+)
+
+// ShellQuote quotes the value in single quotes, in which POSIX shells take
+// every character literally. Single quotes themselves are closed, escaped and
+// reopened.
+func ShellQuote(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", `'\''`) + "'"
+}
+
+// renderSh renders an exported variable of POSIX shells.
+func renderSh(key string, val string) (string, error) {
+	err := requireShellName(key)
+	if err != nil {
+		return "", err
+	}
+	return "export " + key + "=" + ShellQuote(val) + "\n", nil
+}
+
+var fishEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// renderFish renders a global exported variable of the fish shell, in whose
+// single quotes only backslashes and single quotes have to be escaped.
+func renderFish(key string, val string) (string, error) {
+	err := requireShellName(key)
+	if err != nil {
+		return "", err
+	}
+	return "set -gx " + key + " '" + fishEscaper.Replace(val) + "'\n", nil
+}
+
+// PowerShell treats the typographic single quotes like the ASCII one, so all
+// of them are escaped by doubling.
+var powerShellEscaper = strings.NewReplacer(
+	"'", "''",
+	"‘", "‘‘",
+	"’", "’’",
+	"‚", "‚‚",
+	"‛", "‛‛",
+)
+
+var powerShellNameEscaper = strings.NewReplacer("`", "``", "{", "`{", "}", "`}")
+
+// renderPowerShell renders an assignment of an environment variable of the
+// process. Keys which are not plain names use the braced variable syntax.
+func renderPowerShell(key string, val string) (string, error) {
+	name := "$env:" + key
+	if !IsShellName(key) {
+		name = "${env:" + powerShellNameEscaper.Replace(key) + "}"
+	}
+	return name + " = '" + powerShellEscaper.Replace(val) + "'\n", nil
+}
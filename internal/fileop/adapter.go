@@ -2,6 +2,7 @@ package fileop
 
 import (
 	"bufio"
+	"bytes"
 	"github.com/oxio/kvf/internal/lock"
 	"io"
 	"os"
@@ -15,6 +16,15 @@ type FileAdapter interface {
 		updateCallback UpdateFunc,
 		writeCallback WriterFunc,
 	) error
+	NewUpdate(
+		readCallback ReaderFunc,
+		updateCallback UpdateFunc,
+		writeCallback WriterFunc,
+	) *Update
+	NewRemoval(
+		readCallback ReaderFunc,
+		updateCallback UpdateFunc,
+	) *Update
 }
 
 type ReaderFunc func(line string) error
@@ -35,55 +45,46 @@ func NewFileAdapter(filePath string, noErrOnInaccessibleFile bool) *DefaultAdapt
 	}
 }
 
-func (adapter *DefaultAdapter) ReadByLine(lineCallback ReaderFunc) error {
-	fp, err := os.Open(adapter.filePath)
+func (adapter *DefaultAdapter) ReadByLine(lineCallback ReaderFunc) (err error) {
+	// The lock is taken before opening the file, as writers replace the file
+	// and a descriptor opened earlier would still point to the old content.
+	l, err := lock.NewShared(adapter.filePath)
 	if err != nil {
-		if adapter.noErrOnInaccessibleFile && (os.IsNotExist(err) || os.IsPermission(err)) {
-			return nil
-		}
 		return err
 	}
-	defer func(file *os.File) {
-		err = file.Close()
-	}(fp)
+	defer releaseLock(l, &err)
 
-	l, err := lock.New(fp.Name())
+	fp, err := os.Open(adapter.filePath)
 	if err != nil {
+		if adapter.noErrOnInaccessibleFile && (os.IsNotExist(err) || os.IsPermission(err)) {
+			return nil
+		}
 		return err
 	}
-	defer func() {
-		err = l.Release()
-	}()
-
-	err = adapter.readLine(fp, lineCallback)
+	defer closeFile(fp, &err)
 
-	return err
+	return adapter.readLine(fp, lineCallback)
 }
 
-func (adapter *DefaultAdapter) EnsureReadByLine(lineCallback ReaderFunc) error {
-	fp, err := os.OpenFile(adapter.filePath, os.O_RDONLY|os.O_CREATE, 0644)
+func (adapter *DefaultAdapter) EnsureReadByLine(lineCallback ReaderFunc) (err error) {
+	l, err := lock.NewShared(adapter.filePath)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err = file.Close()
-	}(fp)
+	defer releaseLock(l, &err)
 
-	l, err := lock.New(fp.Name())
+	fp, err := os.OpenFile(adapter.filePath, os.O_RDONLY|os.O_CREATE, 0644)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err = l.Release()
-	}()
-
-	err = adapter.readLine(fp, lineCallback)
+	defer closeFile(fp, &err)
 
-	return err
+	return adapter.readLine(fp, lineCallback)
 }
 
-func (adapter *DefaultAdapter) readLine(fp *os.File, lineCallback ReaderFunc) (err error) {
+func (adapter *DefaultAdapter) readLine(fp io.Reader, lineCallback ReaderFunc) (err error) {
 	scanner := bufio.NewScanner(fp)
+	scanner.Split(scanLinesKeepCR)
 	for scanner.Scan() {
 		line := scanner.Text()
 		err = lineCallback(line)
@@ -92,55 +93,102 @@ func (adapter *DefaultAdapter) readLine(fp *os.File, lineCallback ReaderFunc) (e
 		}
 	}
 
-	return err
+	return scanner.Err()
 }
 
+// scanLinesKeepCR works like bufio.ScanLines but keeps the carriage return of
+// CRLF line endings, so they are written back unchanged.
+func scanLinesKeepCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// EnsureUpdate reads the file, lets the caller update its content and writes
+// it back while holding the lock. The new content is written to a temporary
+// file which then atomically replaces the original one, so the file is never
+// left half-written.
 func (adapter *DefaultAdapter) EnsureUpdate(
 	readCallback ReaderFunc,
 	updateCallback UpdateFunc,
 	writeCallback WriterFunc,
 ) error {
-	l, err := lock.New(adapter.filePath)
-	if err != nil {
-		return err
+	return EnsureUpdateAll(adapter.NewUpdate(readCallback, updateCallback, writeCallback))
+}
+
+func (adapter *DefaultAdapter) NewUpdate(
+	readCallback ReaderFunc,
+	updateCallback UpdateFunc,
+	writeCallback WriterFunc,
+) *Update {
+	return &Update{
+		adapter: adapter,
+		read:    readCallback,
+		update:  updateCallback,
+		write:   writeCallback,
 	}
-	defer func() {
-		err = l.Release()
-	}()
+}
+
+// NewRemoval works like NewUpdate, but the file is removed when the update
+// commits instead of being written back.
+func (adapter *DefaultAdapter) NewRemoval(
+	readCallback ReaderFunc,
+	updateCallback UpdateFunc,
+) *Update {
+	return adapter.NewUpdate(readCallback, updateCallback, nil)
+}
 
-	fp, err := os.OpenFile(adapter.filePath, os.O_RDWR|os.O_CREATE, 0644)
+// writeInPlace rewrites the file through its existing inode. It is used when
+// the file cannot be replaced, e.g. when its directory is not writable.
+func writeInPlace(filePath string, writeCallback WriterFunc) (err error) {
+	fp, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err = file.Close()
-	}(fp)
+	defer closeFile(fp, &err)
 
 	writer := bufio.NewWriter(fp)
-	defer func(writer *bufio.Writer) {
-		err = writer.Flush()
-	}(writer)
 
-	err = adapter.readLine(fp, readCallback)
+	_, err = fp.Seek(0, io.SeekStart)
 	if err != nil {
 		return err
 	}
 
-	err = updateCallback()
+	bytesWritten, err := writeCallback(writer)
 	if err != nil {
 		return err
 	}
 
-	_, err = fp.Seek(0, io.SeekStart)
+	err = writer.Flush()
 	if err != nil {
 		return err
 	}
 
-	bytesWritten, err := writeCallback(writer)
+	err = fp.Truncate(bytesWritten)
 	if err != nil {
 		return err
 	}
-	err = fp.Truncate(bytesWritten)
 
-	return err
+	return fp.Sync()
+}
+
+func releaseLock(l *lock.Lock, err *error) {
+	releaseErr := l.Release()
+	if *err == nil {
+		*err = releaseErr
+	}
+}
+
+func closeFile(fp *os.File, err *error) {
+	closeErr := fp.Close()
+	if *err == nil {
+		*err = closeErr
+	}
 }
@@ -0,0 +1,101 @@
+package fileop
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var errAtomicWriteUnsupported = errors.New("atomic write is not supported for the file")
+
+// resolveTarget returns the path of the file that should be replaced. For
+// symlinks it is the file the link points to, so the link itself is kept.
+func resolveTarget(filePath string) (string, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return filePath, nil
+	}
+	return filepath.EvalSymlinks(filePath)
+}
+
+type atomicWrite struct {
+	tmpPath string
+	target  string
+}
+
+// stageAtomic writes the content into a temporary file in the same directory
+// as the target and syncs it. The original mode, owner and group are kept.
+// errAtomicWriteUnsupported is returned when the temporary file cannot be
+// created or made to match the target.
+func stageAtomic(target string, writeCallback WriterFunc) (staged *atomicWrite, err error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errAtomicWriteUnsupported
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return nil, errAtomicWriteUnsupported
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	err = tmp.Chmod(info.Mode().Perm())
+	if err != nil {
+		return nil, errAtomicWriteUnsupported
+	}
+
+	err = copyOwner(tmp, info)
+	if err != nil {
+		return nil, errAtomicWriteUnsupported
+	}
+
+	writer := bufio.NewWriter(tmp)
+	_, err = writeCallback(writer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicWrite{tmpPath: tmp.Name(), target: target}, nil
+}
+
+// commit renames the temporary file over the target.
+func (w *atomicWrite) commit() error {
+	err := os.Rename(w.tmpPath, w.target)
+	if err != nil {
+		w.abort()
+		return err
+	}
+
+	return syncDir(filepath.Dir(w.target))
+}
+
+func (w *atomicWrite) abort() {
+	_ = os.Remove(w.tmpPath)
+}
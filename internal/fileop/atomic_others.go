@@ -0,0 +1,13 @@
+//go:build !unix
+
+package fileop
+
+import "os"
+
+func copyOwner(*os.File, os.FileInfo) error {
+	return nil
+}
+
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package fileop
+
+import (
+	"os"
+	"syscall"
+)
+
+func copyOwner(fp *os.File, info os.FileInfo) error {
+	want, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	current, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	got, ok := current.Sys().(*syscall.Stat_t)
+	if ok && got.Uid == want.Uid && got.Gid == want.Gid {
+		return nil
+	}
+
+	return fp.Chown(int(want.Uid), int(want.Gid))
+}
+
+func syncDir(dir string) (err error) {
+	fp, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer closeFile(fp, &err)
+
+	return fp.Sync()
+}
@@ -0,0 +1,277 @@
+package fileop
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/oxio/kvf/internal/lock"
+	"os"
+	"path/filepath"
+)
+
+// Update is a read-update-write cycle of a single file, which can be applied
+// together with updates of other files by EnsureUpdateAll.
+type Update struct {
+	adapter  *DefaultAdapter
+	read     ReaderFunc
+	update   UpdateFunc
+	write    WriterFunc
+	original []byte
+	// existed tells whether the file existed before the update. Files which
+	// did not are only created when the update is committed.
+	existed bool
+}
+
+type stagedWrite interface {
+	commit() error
+	abort()
+}
+
+// EnsureUpdateAll applies the updates as a single transaction. The locks of
+// all files are taken in a deterministic order, so concurrent transactions
+// can't deadlock, and the new content of every file is staged before any of
+// them is replaced. Missing files are created only then. When replacing one of
+// the files fails, the files touched so far are restored and the created ones
+// removed, so a failed transaction leaves the files as they were.
+func EnsureUpdateAll(updates ...*Update) (err error) {
+	filePaths := make([]string, 0, len(updates))
+	for _, u := range updates {
+		filePaths = append(filePaths, u.adapter.filePath)
+	}
+
+	locks, err := lock.NewAll(filePaths)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, l := range locks {
+			releaseLock(l, &err)
+		}
+	}()
+
+	for _, u := range updates {
+		err = u.readAndUpdate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Files whose content doesn't change are left alone, so that they keep
+	// their inode and modification time.
+	changed := make([]*Update, 0, len(updates))
+	for _, u := range updates {
+		var ok bool
+		ok, err = u.encode()
+		if err != nil {
+			return err
+		}
+		if ok {
+			changed = append(changed, u)
+		}
+	}
+
+	staged := make([]stagedWrite, 0, len(changed))
+	defer func() {
+		if err != nil {
+			for _, s := range staged {
+				s.abort()
+			}
+		}
+	}()
+	for _, u := range changed {
+		var s stagedWrite
+		s, err = u.stage(u.write)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, s)
+	}
+
+	for i, s := range staged {
+		err = s.commit()
+		if err != nil {
+			staged = staged[i+1:]
+			// A file written in place may be changed partially, so the file
+			// which failed is restored too. A file which failed to be created
+			// is removed by its commit.
+			restored := changed[:i]
+			if changed[i].existed {
+				restored = changed[:i+1]
+			}
+			return errors.Join(err, rollback(restored))
+		}
+	}
+
+	return nil
+}
+
+func (u *Update) readAndUpdate() (err error) {
+	u.original, err = os.ReadFile(u.adapter.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	u.existed = err == nil
+
+	err = u.adapter.readLine(bytes.NewReader(u.original), u.read)
+	if err != nil {
+		return err
+	}
+
+	return u.update()
+}
+
+// encode writes the new content of an existing file to memory, so that it can
+// be compared with the original one, and reports whether the file changes.
+// Removing and creating a file always changes it.
+func (u *Update) encode() (bool, error) {
+	if u.write == nil || !u.existed {
+		return true, nil
+	}
+
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	_, err := u.write(writer)
+	if err != nil {
+		return false, err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(buf.Bytes(), u.original) {
+		return false, nil
+	}
+	u.write = writeContent(buf.Bytes())
+	return true, nil
+}
+
+// stage prepares writing the file. The content is written to a temporary file
+// right away, unless the file can only be rewritten in place. Without a write
+// callback the file is removed.
+func (u *Update) stage(writeCallback WriterFunc) (stagedWrite, error) {
+	if writeCallback == nil {
+		if !u.existed {
+			return nil, &os.PathError{Op: "remove", Path: u.adapter.filePath, Err: os.ErrNotExist}
+		}
+		return &removal{filePath: u.adapter.filePath}, nil
+	}
+	if !u.existed {
+		return &createWrite{filePath: u.adapter.filePath, write: writeCallback}, nil
+	}
+
+	target, err := resolveTarget(u.adapter.filePath)
+	if err != nil {
+		target = u.adapter.filePath
+	} else {
+		var s stagedWrite
+		s, err = stageAtomic(target, writeCallback)
+		if err == nil {
+			return s, nil
+		}
+		if err != errAtomicWriteUnsupported {
+			return nil, err
+		}
+	}
+
+	return &inPlaceWrite{filePath: target, write: writeCallback}, nil
+}
+
+// rollback restores the original content of files which were already
+// replaced, or may be partially rewritten, and removes the files which were
+// created.
+func rollback(updates []*Update) error {
+	var errs []error
+	for _, u := range updates {
+		if !u.existed {
+			err := os.Remove(u.adapter.filePath)
+			if err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		s, err := u.stage(writeContent(u.original))
+		if err == nil {
+			err = s.commit()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func writeContent(content []byte) WriterFunc {
+	return func(writer *bufio.Writer) (int64, error) {
+		n, err := writer.Write(content)
+		return int64(n), err
+	}
+}
+
+type inPlaceWrite struct {
+	filePath string
+	write    WriterFunc
+}
+
+func (w *inPlaceWrite) commit() error {
+	return writeInPlace(w.filePath, w.write)
+}
+
+func (w *inPlaceWrite) abort() {
+}
+
+// createWrite creates a file which did not exist when the transaction started.
+type createWrite struct {
+	filePath string
+	write    WriterFunc
+}
+
+func (w *createWrite) commit() (err error) {
+	fp, err := os.OpenFile(w.filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeFile(fp, &err)
+		if err != nil {
+			_ = os.Remove(w.filePath)
+		}
+	}()
+
+	writer := bufio.NewWriter(fp)
+	_, err = w.write(writer)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	err = fp.Sync()
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(w.filePath))
+}
+
+func (w *createWrite) abort() {
+}
+
+// removal removes a file when the transaction commits.
+type removal struct {
+	filePath string
+}
+
+func (r *removal) commit() error {
+	err := os.Remove(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(r.filePath))
+}
+
+func (r *removal) abort() {
+}
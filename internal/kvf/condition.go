@@ -0,0 +1,95 @@
+package kvf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/OneOfOne/xxhash"
+	"github.com/oxio/kvf/internal/parser"
+)
+
+var (
+	ErrConditionFailed = errors.New("condition failed")
+)
+
+// Condition decides whether a file may be updated, given the items and the
+// version of its current content. It is checked while the file is locked, so
+// the file can't change between the check and the update.
+type Condition func(items []*parser.Item, version string) error
+
+// IfAbsent holds when none of the keys is set.
+func IfAbsent(keys ...string) Condition {
+	return func(items []*parser.Item, version string) error {
+		for _, key := range keys {
+			if findItem(items, key) != nil {
+				return fmt.Errorf("%w: key already exists: %s", ErrConditionFailed, key)
+			}
+		}
+		return nil
+	}
+}
+
+// IfPresent holds when all the keys are set.
+func IfPresent(keys ...string) Condition {
+	return func(items []*parser.Item, version string) error {
+		for _, key := range keys {
+			if findItem(items, key) == nil {
+				return fmt.Errorf("%w: key not found: %s", ErrConditionFailed, key)
+			}
+		}
+		return nil
+	}
+}
+
+// IfValue holds when the key is set to the expected value.
+func IfValue(key string, expected string) Condition {
+	return func(items []*parser.Item, version string) error {
+		item := findItem(items, key)
+		if item == nil {
+			return fmt.Errorf("%w: key not found: %s", ErrConditionFailed, key)
+		}
+		if item.Val != expected {
+			return fmt.Errorf("%w: unexpected value of key: %s", ErrConditionFailed, key)
+		}
+		return nil
+	}
+}
+
+// IfVersion holds when the content of the file has the expected version, i.e.
+// the file has not changed since the version was read.
+func IfVersion(expected string) Condition {
+	return func(items []*parser.Item, version string) error {
+		if version != expected {
+			return fmt.Errorf("%w: file has changed, version %s", ErrConditionFailed, version)
+		}
+		return nil
+	}
+}
+
+// findItem returns the first item with the key, the same one Get returns.
+func findItem(items []*parser.Item, key string) *parser.Item {
+	for _, item := range items {
+		if item.IsKeyValue() && item.Key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// versionHash computes the version of a file from its lines. Equal content
+// always results in the same version.
+type versionHash struct {
+	hash *xxhash.XXHash64
+}
+
+func newVersionHash() *versionHash {
+	return &versionHash{hash: xxhash.New64()}
+}
+
+func (v *versionHash) add(line string) {
+	_, _ = v.hash.WriteString(line)
+	_, _ = v.hash.WriteString("\n")
+}
+
+func (v *versionHash) String() string {
+	return fmt.Sprintf("%016x", v.hash.Sum64())
+}
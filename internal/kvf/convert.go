@@ -0,0 +1,156 @@
+package kvf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/fileop"
+	"github.com/oxio/kvf/internal/lock"
+	"github.com/oxio/kvf/internal/parser"
+	"os"
+	"strings"
+)
+
+// Convert converts the items of a file to the format of the codec. Keys keep
+// their first value, the one Get returns, and comments are kept where the
+// format allows it. The warnings describe anything which is lost: comments,
+// later values of duplicate keys, and keys or values the format can't hold.
+func Convert(items []*parser.Item, codec parser.Codec) ([]*parser.Item, []string, error) {
+	var converted, kept []*parser.Item
+	var warnings []string
+	seen := make(map[string]bool)
+	comments := 0
+
+	for _, item := range items {
+		switch {
+		case item.IsEmpty:
+			converted = append(converted, &parser.Item{IsEmpty: true})
+			continue
+		case item.IsComment:
+			converted = append(converted, &parser.Item{IsComment: true, Val: item.Val})
+			comments++
+			continue
+		case item.IsSection:
+			// Sections are part of the keys.
+			continue
+		}
+
+		if seen[item.Key] {
+			warnings = append(warnings, fmt.Sprintf("duplicate key %s is dropped, its first value is kept", item.Key))
+			continue
+		}
+		seen[item.Key] = true
+
+		added, err := codec.Add(converted, item)
+		if errors.Is(err, parser.ErrInvalidKey) || errors.Is(err, parser.ErrUnsupportedValue) {
+			warnings = append(warnings, fmt.Sprintf("%v, the key is dropped", err))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		converted = added
+		kept = append(kept, item)
+	}
+
+	read, err := readBack(converted, codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return converted, append(warnings, compare(kept, comments, read)...), nil
+}
+
+// readBack encodes the items and decodes them again, the way they are read
+// from a file.
+func readBack(items []*parser.Item, codec parser.Codec) ([]*parser.Item, error) {
+	var b strings.Builder
+	_, err := codec.Encode(&b, items)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := parser.NewItemCollection()
+	d := codec.NewDecoder()
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		item, err := d.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			collection.Add(item)
+		}
+	}
+	return *collection.Items, closeDecoder(d, collection)
+}
+
+// compare reports the differences between the kept items and the items which
+// were read back.
+func compare(kept []*parser.Item, comments int, readBack []*parser.Item) []string {
+	var warnings []string
+
+	for _, item := range readBack {
+		if item.IsComment {
+			comments--
+		}
+	}
+	if comments > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d comment(s) dropped", comments))
+	}
+
+	for _, original := range kept {
+		item := findItem(readBack, original.Key)
+		switch {
+		case item == nil:
+			warnings = append(warnings, fmt.Sprintf("key %s does not read back", original.Key))
+		case item.Val != original.Val:
+			warnings = append(warnings, fmt.Sprintf("value of %s reads back as %q", original.Key, item.Val))
+		case original.Comment != "" && item.Comment == "":
+			warnings = append(warnings, fmt.Sprintf("inline comment of %s dropped", original.Key))
+		}
+	}
+	return warnings
+}
+
+// ConvertFile converts the file to the format of the codec and replaces it
+// with the target, as a single transaction holding the locks of both files.
+// The target must not exist, unless it is the file itself. The warnings are
+// those of Convert.
+func ConvertFile(source string, target string, codec parser.Codec) ([]string, error) {
+	from := NewRepo(source, false)
+	to := NewRepoWithCodec(target, false, codec)
+
+	var warnings []string
+	items := parser.NewItemCollection()
+	converted := parser.NewItemCollection()
+	d := from.codec.NewDecoder()
+	read := from.makeReader(d, items, newVersionHash())
+	convert := func() error {
+		err := closeDecoder(d, items)
+		if err != nil {
+			return err
+		}
+		*converted.Items, warnings, err = Convert(*items.Items, codec)
+		return err
+	}
+	write := to.makeWriter(converted)
+
+	if lock.CanonicalPath(source) == lock.CanonicalPath(target) {
+		err := fileop.EnsureUpdateAll(from.adapter.NewUpdate(read, convert, write))
+		return warnings, err
+	}
+
+	// The target is created before the file is removed, so that the file is
+	// kept when creating the target fails.
+	create := to.adapter.NewUpdate(func(string) error { return nil }, func() error {
+		_, err := os.Lstat(target)
+		if err == nil {
+			return fmt.Errorf("%w: %s", os.ErrExist, target)
+		}
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}, write)
+	remove := from.adapter.NewRemoval(read, convert)
+	err := fileop.EnsureUpdateAll(create, remove)
+	return warnings, err
+}
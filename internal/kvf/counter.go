@@ -0,0 +1,106 @@
+package kvf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/fileop"
+	"github.com/oxio/kvf/internal/parser"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+var (
+	ErrNotANumber = errors.New("value is not an integer")
+	// ErrOutOfRange is a failed condition, so the file is left untouched.
+	ErrOutOfRange = fmt.Errorf("%w: value out of range", ErrConditionFailed)
+)
+
+// Counter describes how Increment updates the value of a key.
+type Counter struct {
+	// By is added to the value.
+	By int64
+	// Init is the value of a key which is not set yet.
+	Init int64
+	// Min and Max bound the new value. A nil bound is the limit of int64.
+	Min *int64
+	Max *int64
+	// Wrap makes values beyond one bound continue from the other one instead
+	// of failing with ErrOutOfRange.
+	Wrap bool
+}
+
+// Increment adds to the integer value of the key and returns the new value.
+// The value is read and written back in a single locked cycle, so concurrent
+// increments are never lost.
+func (r *RepoImpl) Increment(key string, counter Counter) (int64, error) {
+	if "" == key {
+		return 0, parser.ErrEmptyKey
+	}
+
+	var result int64
+	err := r.update(func(collection *parser.ItemCollection) fileop.UpdateFunc {
+		return func() error {
+			item := findItem(*collection.Items, key)
+
+			current := counter.Init
+			if item != nil {
+				var err error
+				current, err = strconv.ParseInt(item.Val, 10, 64)
+				if err != nil {
+					return fmt.Errorf("%w: %s=%s", ErrNotANumber, key, item.Val)
+				}
+			}
+
+			var err error
+			result, err = counter.apply(current)
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, key)
+			}
+
+			val := strconv.FormatInt(result, 10)
+			if item != nil {
+				return r.codec.SetVal(item, val)
+			}
+			added, err := parser.NewItem(key, val)
+			if err != nil {
+				return err
+			}
+			*collection.Items, err = r.codec.Add(*collection.Items, added)
+			return err
+		}
+	})
+
+	return result, err
+}
+
+func (c Counter) apply(current int64) (int64, error) {
+	lower := big.NewInt(math.MinInt64)
+	if c.Min != nil {
+		lower.SetInt64(*c.Min)
+	}
+	upper := big.NewInt(math.MaxInt64)
+	if c.Max != nil {
+		upper.SetInt64(*c.Max)
+	}
+	if lower.Cmp(upper) > 0 {
+		return 0, fmt.Errorf("minimum %s is greater than maximum %s", lower, upper)
+	}
+
+	val := new(big.Int).Add(big.NewInt(current), big.NewInt(c.By))
+	if val.Cmp(lower) >= 0 && val.Cmp(upper) <= 0 {
+		return val.Int64(), nil
+	}
+	if !c.Wrap {
+		return 0, ErrOutOfRange
+	}
+
+	// min + (val - min) mod (max - min + 1), with the modulus always positive.
+	size := new(big.Int).Sub(upper, lower)
+	size.Add(size, big.NewInt(1))
+	val.Sub(val, lower)
+	val.Mod(val, size)
+	val.Add(val, lower)
+
+	return val.Int64(), nil
+}
@@ -0,0 +1,213 @@
+package kvf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/parser"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidReference = errors.New("invalid reference")
+	ErrReferenceCycle   = errors.New("reference cycle")
+	ErrRequiredValue    = errors.New("required value is not set")
+)
+
+// Expander resolves ${VAR}, $VAR, ${VAR:-default}, ${VAR-default},
+// ${VAR:?message} and ${VAR?message} references in values. References are
+// looked up in the given items and, optionally, in the process environment.
+// Single-quoted values are taken literally.
+type Expander struct {
+	items    map[string]*parser.Item
+	useEnv   bool
+	expanded map[string]string
+	stack    []string
+}
+
+func NewExpander(items []*parser.Item, useEnv bool) *Expander {
+	e := &Expander{
+		items:    make(map[string]*parser.Item, len(items)),
+		useEnv:   useEnv,
+		expanded: make(map[string]string),
+	}
+	for _, item := range items {
+		e.items[item.Key] = item
+	}
+	return e
+}
+
+// Expand returns the value of the key with all references resolved.
+func (e *Expander) Expand(key string) (string, error) {
+	item, ok := e.items[key]
+	if !ok {
+		return "", ErrItemNotFound
+	}
+	return e.expandItem(item)
+}
+
+func (e *Expander) expandItem(item *parser.Item) (string, error) {
+	if val, ok := e.expanded[item.Key]; ok {
+		return val, nil
+	}
+	for i, key := range e.stack {
+		if key == item.Key {
+			cycle := append(append([]string{}, e.stack[i:]...), key)
+			return "", fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(cycle, " -> "))
+		}
+	}
+
+	if item.Quote == "'" {
+		e.expanded[item.Key] = item.Val
+		return item.Val, nil
+	}
+
+	e.stack = append(e.stack, item.Key)
+	val, err := e.expandText(item.RawVal(), item.Quote == "\"")
+	e.stack = e.stack[:len(e.stack)-1]
+	if err != nil {
+		return "", err
+	}
+
+	e.expanded[item.Key] = val
+	return val, nil
+}
+
+func (e *Expander) lookup(name string) (val string, isSet bool, err error) {
+	if item, ok := e.items[name]; ok {
+		val, err = e.expandItem(item)
+		return val, true, err
+	}
+	if e.useEnv {
+		val, isSet = os.LookupEnv(name)
+	}
+	return val, isSet, nil
+}
+
+// expandText resolves the references of a raw value. Escape sequences are
+// decoded for double-quoted values, where "\$" stands for a literal "$".
+func (e *Expander) expandText(s string, escapes bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escapes && c == '\\' && i+1 < len(s) {
+			b.WriteString(parser.Unescape(s[i : i+2]))
+			i++
+			continue
+		}
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case s[i+1] == '{':
+			end := closingBrace(s, i+2, escapes)
+			if end < 0 {
+				return "", fmt.Errorf("%w: %s", ErrInvalidReference, s[i:])
+			}
+			val, err := e.expandBraced(s[i+2:end], escapes)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+			i = end
+		case isNameStart(s[i+1]):
+			end := i + 2
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			val, _, err := e.lookup(s[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func (e *Expander) expandBraced(expr string, escapes bool) (string, error) {
+	nameEnd := 0
+	for nameEnd < len(expr) && isNameChar(expr[nameEnd]) {
+		nameEnd++
+	}
+	name, op := expr[:nameEnd], expr[nameEnd:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", fmt.Errorf("%w: ${%s}", ErrInvalidReference, expr)
+	}
+
+	val, isSet, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case op == "":
+		return val, nil
+	case strings.HasPrefix(op, ":-"):
+		if !isSet || val == "" {
+			return e.expandText(op[2:], escapes)
+		}
+	case strings.HasPrefix(op, "-"):
+		if !isSet {
+			return e.expandText(op[1:], escapes)
+		}
+	case strings.HasPrefix(op, ":?"):
+		if !isSet || val == "" {
+			return "", e.requiredError(name, op[2:], escapes)
+		}
+	case strings.HasPrefix(op, "?"):
+		if !isSet {
+			return "", e.requiredError(name, op[1:], escapes)
+		}
+	default:
+		return "", fmt.Errorf("%w: ${%s}", ErrInvalidReference, expr)
+	}
+
+	return val, nil
+}
+
+func (e *Expander) requiredError(name string, message string, escapes bool) error {
+	message, err := e.expandText(message, escapes)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		return fmt.Errorf("%w: %s", ErrRequiredValue, name)
+	}
+	return fmt.Errorf("%w: %s: %s", ErrRequiredValue, name, message)
+}
+
+// closingBrace returns the index of the brace closing the reference which
+// starts at the given position, taking nested references into account.
+func closingBrace(s string, start int, escapes bool) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,225 @@
+package kvf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/oxio/kvf/internal/parser"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrNotAnObject  = errors.New("document is not an object")
+	ErrDuplicateKey = errors.New("duplicate key")
+	ErrInvalidKey   = errors.New("invalid key")
+)
+
+// flattener turns nested objects into items whose keys are the paths to the
+// values, joined with the separator. Array elements are keyed by their index.
+// The items keep the order of the document.
+type flattener struct {
+	separator string
+	items     []*parser.Item
+	positions map[string]int
+}
+
+func newFlattener(separator string) *flattener {
+	return &flattener{
+		separator: separator,
+		positions: make(map[string]int),
+	}
+}
+
+func (f *flattener) join(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + f.separator + key
+}
+
+// add adds the value of the key. A weak value, coming from a YAML merge key,
+// gives way to a value set explicitly.
+func (f *flattener) add(key string, val string, weak bool) error {
+	if _, ok := f.positions[key]; ok {
+		if weak {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrDuplicateKey, key)
+	}
+	if !isValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	item, err := parser.NewItem(key, val)
+	if err != nil {
+		return err
+	}
+	f.positions[key] = len(f.items)
+	f.items = append(f.items, item)
+	return nil
+}
+
+// isValidKey reports whether the key can be written to a key-value file and
+// read back.
+func isValidKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "#") && !strings.ContainsFunc(key, func(c rune) bool {
+		return c == '=' || c == '"' || c == '\'' || unicode.IsSpace(c) || unicode.IsControl(c)
+	})
+}
+
+// FlattenJSON reads a JSON object and flattens it into items.
+func FlattenJSON(r io.Reader, separator string) ([]*parser.Item, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, ErrNotAnObject
+	}
+
+	f := newFlattener(separator)
+	err = f.jsonObject(dec, "")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dec.Token()
+	if err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+
+	return f.items, nil
+}
+
+func (f *flattener) jsonObject(dec *json.Decoder, prefix string) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		err = f.jsonValue(dec, f.join(prefix, tok.(string)))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func (f *flattener) jsonValue(dec *json.Decoder, key string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			return f.jsonObject(dec, key)
+		}
+		for i := 0; dec.More(); i++ {
+			err = f.jsonValue(dec, f.join(key, strconv.Itoa(i)))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case string:
+		return f.add(key, t, false)
+	case json.Number:
+		return f.add(key, t.String(), false)
+	case bool:
+		return f.add(key, strconv.FormatBool(t), false)
+	default:
+		// null
+		return f.add(key, "", false)
+	}
+}
+
+// FlattenYAML reads a YAML mapping and flattens it into items. Scalars are
+// taken as they are written, e.g. a number keeps its formatting.
+func FlattenYAML(r io.Reader, separator string) ([]*parser.Item, error) {
+	var doc yaml.Node
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, ErrNotAnObject
+	}
+
+	f := newFlattener(separator)
+	err = f.yamlValue(root, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return f.items, nil
+}
+
+func (f *flattener) yamlValue(node *yaml.Node, key string, weak bool) error {
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		// Merged mappings are applied last, as explicit keys override them.
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Tag == "!!merge" {
+				merged = append(merged, v)
+				continue
+			}
+			err := f.yamlValue(v, f.join(key, k.Value), weak)
+			if err != nil {
+				return err
+			}
+		}
+		for _, m := range merged {
+			m = resolveAlias(m)
+			sources := []*yaml.Node{m}
+			if m.Kind == yaml.SequenceNode {
+				sources = m.Content
+			}
+			for _, source := range sources {
+				err := f.yamlValue(source, key, true)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		for i, element := range node.Content {
+			err := f.yamlValue(element, f.join(key, strconv.Itoa(i)), weak)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if node.Tag == "!!null" {
+			return f.add(key, "", weak)
+		}
+		return f.add(key, node.Value, weak)
+	}
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
@@ -0,0 +1,12 @@
+package kvf
+
+import "github.com/oxio/kvf/internal/parser"
+
+// format is the codec repos use regardless of the extension of their files.
+var format parser.Codec
+
+// SetFormat makes new repos read and write their files with the codec. A nil
+// codec restores the choice by the extension of each file.
+func SetFormat(codec parser.Codec) {
+	format = codec
+}
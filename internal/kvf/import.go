@@ -0,0 +1,49 @@
+package kvf
+
+import (
+	"fmt"
+	"github.com/oxio/kvf/internal/fileop"
+	"github.com/oxio/kvf/internal/parser"
+)
+
+// MergePolicy decides what Import does with keys which are already set.
+type MergePolicy int
+
+const (
+	// Overwrite replaces the existing values.
+	Overwrite MergePolicy = iota
+	// KeepExisting leaves the existing values as they are.
+	KeepExisting
+	// FailOnConflict fails the import when an existing value differs from the
+	// imported one.
+	FailOnConflict
+)
+
+// ErrConflict is a failed condition, so the file is left untouched.
+var ErrConflict = fmt.Errorf("%w: conflicting value", ErrConditionFailed)
+
+// Import sets the items following the merge policy, all in a single locked
+// read-update-write cycle.
+func (r *RepoImpl) Import(policy MergePolicy, items ...*parser.Item) error {
+	return r.update(func(collection *parser.ItemCollection) fileop.UpdateFunc {
+		return func() error {
+			incoming := make([]*parser.Item, 0, len(items))
+			for _, item := range items {
+				existing := findItem(*collection.Items, item.Key)
+				if existing != nil {
+					switch policy {
+					case KeepExisting:
+						continue
+					case FailOnConflict:
+						if existing.Val != item.Val {
+							return fmt.Errorf("%w: %s", ErrConflict, item.Key)
+						}
+						continue
+					}
+				}
+				incoming = append(incoming, item)
+			}
+			return r.makeUpdater(collection, incoming)()
+		}
+	})
+}
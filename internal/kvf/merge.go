@@ -0,0 +1,74 @@
+package kvf
+
+import "github.com/oxio/kvf/internal/parser"
+
+// Layer holds the keys of a single file, each with its first occurrence within
+// the file, the one Get returns.
+type Layer struct {
+	File  string
+	Items []*parser.Item
+}
+
+// Find returns the item of the key, or nil if the file does not have the key.
+func (l *Layer) Find(key string) *parser.Item {
+	return findItem(l.Items, key)
+}
+
+// ReadLayers reads the files in order.
+func ReadLayers(files []string, noErrorOnInaccessibleFile bool) ([]*Layer, error) {
+	layers := make([]*Layer, 0, len(files))
+
+	for _, file := range files {
+		items, err := NewRepo(file, noErrorOnInaccessibleFile).FindAll()
+		if err != nil {
+			return nil, err
+		}
+
+		layer := &Layer{File: file}
+		seen := make(map[string]bool)
+		for _, item := range *items {
+			if !item.IsKeyValue() || seen[item.Key] {
+				continue
+			}
+			seen[item.Key] = true
+			layer.Items = append(layer.Items, item)
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// Merge merges the items of the layers. The last layer containing a key wins,
+// or the first one if firstWins is set. Keys keep the order of their first
+// appearance.
+func Merge(layers []*Layer, firstWins bool) []*parser.Item {
+	var resolved []*parser.Item
+	positions := make(map[string]int)
+
+	for _, layer := range layers {
+		for _, item := range layer.Items {
+			if pos, ok := positions[item.Key]; ok {
+				if !firstWins {
+					resolved[pos] = item
+				}
+				continue
+			}
+			positions[item.Key] = len(resolved)
+			resolved = append(resolved, item)
+		}
+	}
+
+	return resolved
+}
+
+// ResolveAll reads the files in order and merges their items the same way
+// "get" does: the first occurrence of a key within a file is used and the last
+// file containing the key wins. Keys keep the order of their first appearance.
+func ResolveAll(files []string, noErrorOnInaccessibleFile bool) ([]*parser.Item, error) {
+	layers, err := ReadLayers(files, noErrorOnInaccessibleFile)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(layers, false), nil
+}
@@ -10,7 +10,17 @@ import (
 
 type Repo interface {
 	Get(key string) (*parser.Item, error)
+	GetWithVersion(key string) (*parser.Item, string, error)
 	Set(item *parser.Item) error
+	SetMany(items ...*parser.Item) error
+	SetIf(condition Condition, items ...*parser.Item) error
+	SetIfAbsent(item *parser.Item) error
+	SetIfPresent(item *parser.Item) error
+	SetIfVersion(version string, items ...*parser.Item) error
+	CompareAndSwap(item *parser.Item, expected string) error
+	Increment(key string, counter Counter) (int64, error)
+	Import(policy MergePolicy, items ...*parser.Item) error
+	Delete(keys []string, mustExist bool) error
 }
 
 var _ Repo = &RepoImpl{}
@@ -21,19 +31,29 @@ var (
 
 type RepoImpl struct {
 	adapter fileop.FileAdapter
-	parser  *parser.LineParser
+	codec   parser.Codec
 }
 
+// NewRepo returns the repo of the file, read and written in the format set by
+// SetFormat, or else in the format of its extension.
 func NewRepo(filePath string, noErrorOnInaccessibleFile bool) *RepoImpl {
+	codec := format
+	if codec == nil {
+		codec = parser.CodecOf(filePath)
+	}
+	return NewRepoWithCodec(filePath, noErrorOnInaccessibleFile, codec)
+}
+
+func NewRepoWithCodec(filePath string, noErrorOnInaccessibleFile bool, codec parser.Codec) *RepoImpl {
 	return &RepoImpl{
 		adapter: fileop.NewFileAdapter(filePath, noErrorOnInaccessibleFile),
-		parser:  parser.NewLineParser(),
+		codec:   codec,
 	}
 }
 
 func (r *RepoImpl) FindAll() (*[]*parser.Item, error) {
 	var collection = parser.NewItemCollection()
-	err := r.adapter.ReadByLine(r.makeReader(collection))
+	_, err := r.read(collection)
 	if err != nil {
 		return nil, err
 	}
@@ -41,75 +61,234 @@ func (r *RepoImpl) FindAll() (*[]*parser.Item, error) {
 }
 
 func (r *RepoImpl) Get(key string) (*parser.Item, error) {
+	item, _, err := r.GetWithVersion(key)
+	return item, err
+}
+
+// GetWithVersion returns the item along with the version of the file it was
+// read from. The version can be passed to SetIfVersion. It is returned also
+// when the item is not found.
+func (r *RepoImpl) GetWithVersion(key string) (*parser.Item, string, error) {
 	if "" == key {
-		return nil, fmt.Errorf("key is empty")
+		return nil, "", parser.ErrEmptyKey
 	}
 
 	var collection = parser.NewItemCollection()
-	err := r.adapter.ReadByLine(r.makeReader(collection))
+	version, err := r.read(collection)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	for _, item := range *collection.Items {
-		if item.IsEmpty || item.IsComment {
-			continue
-		}
-		if item.Key == key {
-			return item, nil
-		}
+	item := findItem(*collection.Items, key)
+	if item == nil {
+		return nil, version, ErrItemNotFound
 	}
 
-	return nil, ErrItemNotFound
+	return item, version, nil
 }
 
 func (r *RepoImpl) Set(item *parser.Item) error {
-	var collection = parser.NewItemCollection()
-	read := r.makeReader(collection)
-	update := r.makeUpdater(collection, item)
+	return r.SetMany(item)
+}
+
+// SetMany sets all the items in a single locked read-update-write cycle.
+func (r *RepoImpl) SetMany(items ...*parser.Item) error {
+	return SetAll([]*RepoImpl{r}, items...)
+}
+
+// SetIf sets the items only when the condition holds. ErrConditionFailed is
+// returned otherwise and the file is left untouched.
+func (r *RepoImpl) SetIf(condition Condition, items ...*parser.Item) error {
+	return SetAllIf([]*RepoImpl{r}, condition, items...)
+}
+
+func (r *RepoImpl) SetIfAbsent(item *parser.Item) error {
+	return r.SetIf(IfAbsent(item.Key), item)
+}
+
+func (r *RepoImpl) SetIfPresent(item *parser.Item) error {
+	return r.SetIf(IfPresent(item.Key), item)
+}
+
+// SetIfVersion sets the items only when the file has not changed since the
+// version was returned by GetWithVersion.
+func (r *RepoImpl) SetIfVersion(version string, items ...*parser.Item) error {
+	return r.SetIf(IfVersion(version), items...)
+}
+
+// CompareAndSwap sets the item only when its current value is the expected
+// one.
+func (r *RepoImpl) CompareAndSwap(item *parser.Item, expected string) error {
+	return r.SetIf(IfValue(item.Key, expected), item)
+}
+
+// SetAll sets the items in every repo as a single transaction: either all of
+// the files are updated or none of them is.
+func SetAll(repos []*RepoImpl, items ...*parser.Item) error {
+	return SetAllIf(repos, nil, items...)
+}
+
+// SetAllIf works like SetAll, but only when the condition holds for every
+// file. A nil condition always holds.
+func SetAllIf(repos []*RepoImpl, condition Condition, items ...*parser.Item) error {
+	updates := make([]*fileop.Update, 0, len(repos))
+	for _, r := range repos {
+		updates = append(updates, r.prepare(condition, func(collection *parser.ItemCollection) fileop.UpdateFunc {
+			return r.makeUpdater(collection, items)
+		}))
+	}
+	return fileop.EnsureUpdateAll(updates...)
+}
+
+func (r *RepoImpl) Delete(keys []string, mustExist bool) error {
+	return DeleteAll([]*RepoImpl{r}, keys, mustExist)
+}
+
+// DeleteAll deletes the keys from every repo as a single transaction: either
+// all of the files are updated or none of them is, e.g. when a key which must
+// exist is missing in one of them.
+func DeleteAll(repos []*RepoImpl, keys []string, mustExist bool) error {
+	for _, key := range keys {
+		if "" == key {
+			return parser.ErrEmptyKey
+		}
+	}
+
+	updates := make([]*fileop.Update, 0, len(repos))
+	for _, r := range repos {
+		updates = append(updates, r.prepare(nil, func(collection *parser.ItemCollection) fileop.UpdateFunc {
+			return r.makeRemover(collection, keys, mustExist)
+		}))
+	}
+	return fileop.EnsureUpdateAll(updates...)
+}
+
+// read reads the file into the collection and returns the version of its
+// content.
+func (r *RepoImpl) read(collection *parser.ItemCollection) (string, error) {
+	d := r.codec.NewDecoder()
+	version := newVersionHash()
+	err := r.adapter.ReadByLine(r.makeReader(d, collection, version))
+	if err != nil {
+		return "", err
+	}
+	return version.String(), closeDecoder(d, collection)
+}
+
+// update reads the file into a collection, applies the update made for it and
+// writes the collection back, all within a single locked cycle.
+func (r *RepoImpl) update(makeUpdate func(collection *parser.ItemCollection) fileop.UpdateFunc) error {
+	return fileop.EnsureUpdateAll(r.prepare(nil, makeUpdate))
+}
+
+// prepare builds the update of the file. The update is made only when the
+// condition, if any, holds for the content read.
+func (r *RepoImpl) prepare(
+	condition Condition,
+	makeUpdate func(collection *parser.ItemCollection) fileop.UpdateFunc,
+) *fileop.Update {
+	collection := parser.NewItemCollection()
+	d := r.codec.NewDecoder()
+	version := newVersionHash()
+	read := r.makeReader(d, collection, version)
+	update := makeUpdate(collection)
 	write := r.makeWriter(collection)
 
-	return r.adapter.EnsureUpdate(read, update, write)
+	return r.adapter.NewUpdate(read, func() error {
+		err := closeDecoder(d, collection)
+		if err != nil {
+			return err
+		}
+		if condition != nil {
+			err = condition(*collection.Items, version.String())
+			if err != nil {
+				return err
+			}
+		}
+		return update()
+	}, write)
+}
+
+// closeDecoder finishes reading, adding the items which were left to the
+// collection.
+func closeDecoder(d parser.Decoder, collection *parser.ItemCollection) error {
+	items, err := d.Close()
+	*collection.Items = append(*collection.Items, items...)
+	return err
 }
 
-func (r *RepoImpl) makeReader(collection *parser.ItemCollection) fileop.ReaderFunc {
+func (r *RepoImpl) makeReader(
+	d parser.Decoder,
+	collection *parser.ItemCollection,
+	version *versionHash,
+) fileop.ReaderFunc {
 	return func(line string) error {
-		item, err := r.parser.Parse(line)
+		version.add(line)
+		item, err := d.Parse(line)
 		if err != nil {
 			return err
 		}
-		*collection.Items = append(*collection.Items, item)
+		if item != nil {
+			*collection.Items = append(*collection.Items, item)
+		}
 		return nil
 	}
 }
 
-func (r *RepoImpl) makeUpdater(collection *parser.ItemCollection, incoming *parser.Item) fileop.UpdateFunc {
+func (r *RepoImpl) makeUpdater(collection *parser.ItemCollection, incoming []*parser.Item) fileop.UpdateFunc {
 	return func() error {
-		found := false
-		for k, item := range *collection.Items {
-			if item.Key == incoming.Key {
-				item.Val = incoming.Val
-				(*collection.Items)[k] = item
-				found = true
-				break
+		for _, in := range incoming {
+			if "" == in.Key {
+				return parser.ErrEmptyKey
+			}
+
+			var err error
+			if item := findItem(*collection.Items, in.Key); item != nil {
+				err = r.codec.SetVal(item, in.Val)
+			} else {
+				*collection.Items, err = r.codec.Add(*collection.Items, in)
+			}
+			if err != nil {
+				return err
 			}
-		}
-		if !found {
-			*collection.Items = append(*collection.Items, incoming)
 		}
 		return nil
 	}
 }
 
-func (r *RepoImpl) makeWriter(collection *parser.ItemCollection) fileop.WriterFunc {
-	return func(writer *bufio.Writer) (bytesWritten int64, err error) {
+func (r *RepoImpl) makeRemover(collection *parser.ItemCollection, keys []string, mustExist bool) fileop.UpdateFunc {
+	return func() error {
+		toRemove := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			toRemove[key] = false
+		}
+
+		kept := make([]*parser.Item, 0, len(*collection.Items))
 		for _, item := range *collection.Items {
-			var n, err = writer.WriteString(item.ToLine())
-			bytesWritten += int64(n)
-			if err != nil {
-				return bytesWritten, err
+			if item.IsKeyValue() {
+				if _, ok := toRemove[item.Key]; ok {
+					toRemove[item.Key] = true
+					continue
+				}
+			}
+			kept = append(kept, item)
+		}
+
+		if mustExist {
+			for _, key := range keys {
+				if !toRemove[key] {
+					return fmt.Errorf("%w: %s", ErrItemNotFound, key)
+				}
 			}
 		}
-		return bytesWritten, nil
+
+		*collection.Items = kept
+		return nil
+	}
+}
+
+func (r *RepoImpl) makeWriter(collection *parser.ItemCollection) fileop.WriterFunc {
+	return func(writer *bufio.Writer) (bytesWritten int64, err error) {
+		return r.codec.Encode(writer, *collection.Items)
 	}
 }
@@ -0,0 +1,201 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DirEnv     = "KVF_LOCK_DIR"
+	SidecarEnv = "KVF_LOCK_SIDECAR"
+	TimeoutEnv = "KVF_LOCK_TIMEOUT"
+)
+
+var (
+	configMu     sync.Mutex
+	dirOverride  string
+	sidecarSet   bool
+	sidecar      bool
+	timeoutSet   bool
+	timeout      time.Duration
+	noWait       bool
+	resolvedDirs = make(map[string]string)
+)
+
+// ResetConfig removes all settings made by the setters, so the environment
+// variables and defaults apply again.
+func ResetConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	dirOverride = ""
+	sidecarSet = false
+	sidecar = false
+	timeoutSet = false
+	timeout = 0
+	noWait = false
+}
+
+// SetDir sets the directory for lock files, taking precedence over the
+// KVF_LOCK_DIR environment variable.
+func SetDir(dir string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	dirOverride = dir
+}
+
+// SetSidecar enables or disables the sidecar mode, taking precedence over the
+// KVF_LOCK_SIDECAR environment variable. In the sidecar mode the lock file of
+// "<file>" is "<file>.lock" next to it, so processes which don't share a lock
+// directory, e.g. containers sharing a volume, still lock each other out.
+func SetSidecar(enabled bool) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	sidecarSet = true
+	sidecar = enabled
+}
+
+// SetTimeout sets how long New and NewAll wait for locks, taking precedence
+// over the KVF_LOCK_TIMEOUT environment variable. Zero means no time limit.
+func SetTimeout(d time.Duration) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	timeoutSet = true
+	timeout = d
+}
+
+// SetNoWait makes New and NewAll fail right away when a lock is held by
+// someone else.
+func SetNoWait(enabled bool) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	noWait = enabled
+}
+
+// defaultContext returns the context limiting the wait of New and NewAll.
+func defaultContext() (context.Context, context.CancelFunc) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if noWait {
+		// An expired context makes the lock to be tried exactly once.
+		return context.WithTimeout(context.Background(), 0)
+	}
+
+	d := timeout
+	if !timeoutSet {
+		d, _ = time.ParseDuration(os.Getenv(TimeoutEnv))
+	}
+	if d > 0 {
+		return context.WithTimeout(context.Background(), d)
+	}
+	return context.Background(), func() {}
+}
+
+func isSidecar() bool {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if sidecarSet {
+		return sidecar
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv(SidecarEnv))
+	return enabled
+}
+
+// lockDir returns the directory for lock files. A configured directory is
+// created when missing. Otherwise the first usable of the default system
+// directory, $XDG_RUNTIME_DIR/kvf and a per-user temporary directory is used.
+func lockDir() (string, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	dir := dirOverride
+	if dir == "" {
+		dir = os.Getenv(DirEnv)
+	}
+	if dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return "", fmt.Errorf("lock directory: %w", err)
+		}
+		return dir, nil
+	}
+
+	candidates := defaultLockDirs()
+	paths := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.path)
+	}
+	key := strings.Join(paths, string(os.PathListSeparator))
+	if resolved, ok := resolvedDirs[key]; ok {
+		return resolved, nil
+	}
+
+	for _, candidate := range candidates {
+		if isUsableDir(candidate) {
+			resolvedDirs[key] = candidate.path
+			return candidate.path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no usable lock directory found in %s, set %s", strings.Join(paths, ", "), DirEnv)
+}
+
+// lockDirCandidate is one of the default directories for lock files. The
+// system directory is shared by all users, the others are private to the user,
+// so that no other user can plant files or symlinks in them.
+type lockDirCandidate struct {
+	path    string
+	private bool
+}
+
+func defaultLockDirs() []lockDirCandidate {
+	if runtime.GOOS == "windows" {
+		return []lockDirCandidate{{path: filepath.Join(os.TempDir(), "kvf-locks")}}
+	}
+
+	dirs := []lockDirCandidate{{path: "/var/lock/kvf"}}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, lockDirCandidate{path: filepath.Join(runtimeDir, "kvf"), private: true})
+	}
+	return append(dirs, lockDirCandidate{
+		path:    filepath.Join(os.TempDir(), "kvf-"+strconv.Itoa(os.Getuid())),
+		private: true,
+	})
+}
+
+func isUsableDir(candidate lockDirCandidate) bool {
+	dir := candidate.path
+	if candidate.private {
+		// A private directory in a shared parent, e.g. /tmp, may have been
+		// created by another user, so it is only used if it is a directory
+		// of the user which no one else can write to.
+		err := os.Mkdir(dir, 0700)
+		if err != nil && !os.IsExist(err) {
+			return false
+		}
+		if !isPrivateDir(dir) {
+			return false
+		}
+	} else {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return false
+		}
+	}
+
+	probe, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return false
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	return true
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// isPrivateDir reports whether the path is a directory, not a symlink to one.
+// The owner and permissions aren't checked, as they don't map to file modes.
+func isPrivateDir(dir string) bool {
+	info, err := os.Lstat(dir)
+	return err == nil && info.IsDir()
+}
@@ -1,60 +1,255 @@
 package lock
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/OneOfOne/xxhash"
 	"github.com/gofrs/flock"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strconv"
 	"time"
 )
 
-var baseLockDir string
+const maxSymlinks = 255
+
+// gateWait is how long a locker waits for the gate at least, even when it
+// doesn't wait for the lock. Other lockers hold the gate only for a moment
+// while passing it, unless a writer waits behind it for the lock.
+const gateWait = 100 * time.Millisecond
+
+var (
+	ErrTimeout = errors.New("timed out waiting for lock")
+)
 
 type Lock struct {
 	lockFilePath string
 	lock         *flock.Flock
+	held         bool
 }
 
+// New locks the file exclusively, waiting for the lock as long as configured
+// by SetTimeout and SetNoWait.
 func New(filePath string) (*Lock, error) {
+	ctx, cancel := defaultContext()
+	defer cancel()
+
+	return NewContext(ctx, filePath)
+}
+
+// NewContext locks the file exclusively, waiting for the lock until the
+// context is done. ErrTimeout is returned when the deadline of the context is
+// exceeded.
+func NewContext(ctx context.Context, filePath string) (*Lock, error) {
 	lockFilePath, err := obtainLockFilePath(filePath)
 	if err != nil {
 		return nil, err
 	}
 
+	return obtain(ctx, lockFilePath, false)
+}
+
+// NewShared locks the file for reading. Any number of shared locks can be held
+// at the same time, while an exclusive lock excludes all other locks.
+func NewShared(filePath string) (*Lock, error) {
+	ctx, cancel := defaultContext()
+	defer cancel()
+
+	return NewSharedContext(ctx, filePath)
+}
+
+// NewSharedContext works like NewShared, waiting for the lock until the
+// context is done.
+func NewSharedContext(ctx context.Context, filePath string) (*Lock, error) {
+	lockFilePath, err := obtainLockFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return obtain(ctx, lockFilePath, true)
+}
+
+// obtain takes the lock. Every locker passes a gate lock first, which is held
+// only until the lock itself is obtained. A writer waiting for the readers to
+// finish keeps the gate closed, so that new readers queue up behind it and
+// can't starve the writer. Locks held by a parent process are not taken again,
+// a nested lock next to them is taken instead.
+func obtain(ctx context.Context, lockFilePath string, shared bool) (*Lock, error) {
+	for isHeld(lockFilePath) {
+		lockFilePath += nestedSuffix
+	}
+
+	gateCtx, cancel := gateContext(ctx)
+	defer cancel()
+	gate := flock.New(lockFilePath + ".gate")
+	err := acquire(gateCtx, gate.Lock, gate.TryLock, lockFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = gate.Unlock()
+	}()
+
 	fileLock := flock.New(lockFilePath)
+	if shared {
+		err = acquire(ctx, fileLock.RLock, fileLock.TryRLock, lockFilePath)
+	} else {
+		err = acquire(ctx, fileLock.Lock, fileLock.TryLock, lockFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lock{
+		lockFilePath: lockFilePath,
+		lock:         fileLock,
+	}, nil
+}
+
+// gateContext extends the deadline of the context to gateWait, so that a
+// locker which doesn't wait fails only when the lock is busy, not when other
+// lockers pass the gate at the same time.
+func gateContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) >= gateWait {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), gateWait)
+}
+
+func acquire(ctx context.Context, lock func() error, tryLock func() (bool, error), lockFilePath string) error {
+	if ctx.Done() == nil {
+		// Nothing can interrupt the wait, so block until the lock is released.
+		return lock()
+	}
 
 	res, err := retryWithBackoff(
-		func() (bool, error) {
-			return true, fileLock.Lock()
-		},
-		10,
+		ctx,
+		tryLock,
 		time.Millisecond,
+		100*time.Millisecond,
 		2.0,
 	)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if !res {
-		return nil, fmt.Errorf("failed to obtain lock for file")
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s", ErrTimeout, lockFilePath)
+		}
+		return ctx.Err()
 	}
 
-	return &Lock{
-		lock: fileLock,
-	}, nil
+	return nil
 }
 
+// NewAll locks all the files. The locks are taken in the order of their lock
+// file paths, so that processes locking the same set of files can't deadlock.
+// Files sharing a lock are locked only once.
+func NewAll(filePaths []string) ([]*Lock, error) {
+	ctx, cancel := defaultContext()
+	defer cancel()
+
+	return NewAllContext(ctx, filePaths)
+}
+
+// NewAllContext works like NewAll, waiting for the locks until the context is
+// done.
+func NewAllContext(ctx context.Context, filePaths []string) ([]*Lock, error) {
+	seen := make(map[string]bool, len(filePaths))
+	ordered := make([]string, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		lockFilePath, err := obtainLockFilePath(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[lockFilePath] {
+			seen[lockFilePath] = true
+			ordered = append(ordered, lockFilePath)
+		}
+	}
+	sort.Strings(ordered)
+
+	locks := make([]*Lock, 0, len(ordered))
+	for _, lockFilePath := range ordered {
+		l, err := obtain(ctx, lockFilePath, false)
+		if err != nil {
+			for _, obtained := range locks {
+				_ = obtained.Release()
+			}
+			return nil, err
+		}
+		locks = append(locks, l)
+	}
+
+	return locks, nil
+}
+
+// Release releases the lock. The token recorded for the children of the
+// process is removed first, so they no longer count the lock as held.
 func (l *Lock) Release() error {
-	return l.lock.Unlock()
+	var err error
+	if l.held {
+		err = os.Remove(l.lockFilePath + heldSuffix)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		l.held = false
+	}
+	return errors.Join(err, l.lock.Unlock())
+}
+
+// CanonicalPath returns the absolute path of the file with all symlinks
+// resolved, so that every path leading to the same file results in the same
+// lock. Files which don't exist yet are resolved as far as possible.
+func CanonicalPath(filePath string) string {
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Clean(filePath)
+	}
+
+	for i := 0; i < maxSymlinks; i++ {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return resolved
+		}
+
+		dir, base := filepath.Split(path)
+		link, err := os.Readlink(path)
+		if err != nil {
+			resolvedDir, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return path
+			}
+			return filepath.Join(resolvedDir, base)
+		}
+
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(dir, link)
+		}
+		path = filepath.Clean(link)
+	}
+
+	return path
 }
 
 func obtainLockFilePath(filePath string) (string, error) {
+	canonicalPath := CanonicalPath(filePath)
+	if isSidecar() {
+		return canonicalPath + ".lock", nil
+	}
+
+	baseLockDir, err := lockDir()
+	if err != nil {
+		return "", err
+	}
+
 	hash := xxhash.New64()
-	_, err := hash.WriteString(filePath)
+	_, err = hash.WriteString(canonicalPath)
 	if err != nil {
 		return "", err
 	}
@@ -64,15 +259,19 @@ func obtainLockFilePath(filePath string) (string, error) {
 	return lockFilePath, nil
 }
 
+// retryWithBackoff calls fn until it succeeds, fails or the context is done.
+// fn is always called at least once. The interval between the calls grows by
+// the factor up to the maximal interval.
 func retryWithBackoff(
+	ctx context.Context,
 	fn func() (bool, error),
-	maxRetries int,
 	initialInterval time.Duration,
+	maxInterval time.Duration,
 	factor float64,
 ) (bool, error) {
 	interval := initialInterval
 
-	for i := 0; i < maxRetries; i++ {
+	for {
 		success, err := fn()
 
 		if err != nil {
@@ -83,24 +282,12 @@ func retryWithBackoff(
 			return true, nil
 		}
 
-		if i < maxRetries-1 {
-			time.Sleep(interval)
-			interval = time.Duration(float64(interval) * factor)
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-time.After(interval):
 		}
-	}
-
-	return false, nil
-}
-
-func init() {
-	if runtime.GOOS == "windows" {
-		baseLockDir = filepath.Join(os.TempDir(), "kvf-locks")
-	} else {
-		baseLockDir = "/var/lock/kvf"
-	}
 
-	err := os.MkdirAll(baseLockDir, 0755)
-	if err != nil && !os.IsExist(err) {
-		panic(err)
+		interval = min(time.Duration(float64(interval)*factor), maxInterval)
 	}
 }
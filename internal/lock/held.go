@@ -0,0 +1,117 @@
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HeldEnv lists the locks held by a parent process on behalf of its children,
+// each as a token followed by "=" and the lock file. The parent writes its
+// process ID and the token into the held file of the lock, so a listed lock
+// counts as held only while the parent is alive and holds it. A child locks a
+// nested lock file instead, so that the children serialize among themselves
+// without waiting for their parent.
+const HeldEnv = "KVF_HELD_LOCKS"
+
+const (
+	heldSuffix   = ".held"
+	nestedSuffix = ".nested"
+)
+
+// Environ returns the environment variables to pass to a child process, so
+// that it reuses the held locks instead of waiting for them. A new token is
+// recorded for each of the locks, which is removed when it is released. The
+// lock settings are passed along too, so the child resolves the same lock
+// files.
+func Environ(locks []*Lock) ([]string, error) {
+	held := filepath.SplitList(os.Getenv(HeldEnv))
+	for _, l := range locks {
+		token, err := l.hold()
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, token+"="+l.lockFilePath)
+	}
+
+	env := []string{HeldEnv + "=" + strings.Join(held, string(os.PathListSeparator))}
+	if isSidecar() {
+		return append(env, SidecarEnv+"="+strconv.FormatBool(true)), nil
+	}
+
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+	return append(env, SidecarEnv+"="+strconv.FormatBool(false), DirEnv+"="+dir), nil
+}
+
+// hold writes the process ID and a new token into the held file of the lock.
+func (l *Lock) hold() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	content := strconv.Itoa(os.Getpid()) + " " + token + "\n"
+	err = writeNew(l.lockFilePath+heldSuffix, []byte(content))
+	if err != nil {
+		return "", err
+	}
+	l.held = true
+	return token, nil
+}
+
+// writeNew replaces the file with a new one. The file is created exclusively,
+// so a symlink planted in its place is never followed.
+func writeNew(path string, content []byte) (err error) {
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	fp, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := fp.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = fp.Write(content)
+	return err
+}
+
+// isHeld reports whether the lock is held on behalf of this process, i.e. the
+// token passed in HeldEnv is the one of a live process holding the lock.
+func isHeld(lockFilePath string) bool {
+	var token string
+	for _, entry := range filepath.SplitList(os.Getenv(HeldEnv)) {
+		t, path, ok := strings.Cut(entry, "=")
+		if ok && path == lockFilePath {
+			token = t
+		}
+	}
+	if token == "" {
+		return false
+	}
+
+	content, err := os.ReadFile(lockFilePath + heldSuffix)
+	if err != nil {
+		return false
+	}
+	pid, heldToken, ok := strings.Cut(strings.TrimSpace(string(content)), " ")
+	if !ok || heldToken != token {
+		return false
+	}
+	n, err := strconv.Atoi(pid)
+	return err == nil && isAlive(n)
+}
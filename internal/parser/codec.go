@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrUnknownFormat    = errors.New("unknown file format")
+	ErrInvalidKey       = errors.New("invalid key")
+	ErrUnsupportedValue = errors.New("unsupported value")
+)
+
+const (
+	FormatEnv        = "env"
+	FormatProperties = "properties"
+	FormatINI        = "ini"
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+)
+
+// Decoder parses the lines of a single file into items.
+type Decoder interface {
+	// Parse parses a single line. It returns a nil item without an error when
+	// more lines are needed for the next item.
+	Parse(line string) (*Item, error)
+	// Close finishes parsing and returns the items which could only be parsed
+	// once all the lines were read.
+	Close() ([]*Item, error)
+}
+
+// Codec reads and writes the items of a file format.
+type Codec interface {
+	// NewDecoder returns a decoder for a single file.
+	NewDecoder() Decoder
+	// SetVal changes the value of an item read from a file, keeping the rest
+	// of its formatting where the format allows it.
+	SetVal(item *Item, val string) error
+	// Add adds an item which is not in the file yet and returns the new
+	// items of the file.
+	Add(items []*Item, item *Item) ([]*Item, error)
+	// Encode writes the items as the content of a file.
+	Encode(w io.Writer, items []*Item) (int64, error)
+}
+
+var codecs = map[string]Codec{
+	FormatEnv:        envCodec{},
+	FormatProperties: propertiesCodec{},
+	FormatINI:        iniCodec{},
+	FormatJSON:       jsonCodec{},
+	FormatYAML:       yamlCodec{},
+}
+
+var extensions = map[string]string{
+	".properties": FormatProperties,
+	".ini":        FormatINI,
+	".json":       FormatJSON,
+	".yaml":       FormatYAML,
+	".yml":        FormatYAML,
+}
+
+// GetCodec returns the codec of the format.
+func GetCodec(format string) (Codec, error) {
+	codec, ok := codecs[format]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownFormat, format, strings.Join(Formats(), ", "))
+	}
+	return codec, nil
+}
+
+// FormatOf returns the format of the file by its extension. Files with other
+// extensions are key-value files.
+func FormatOf(filePath string) string {
+	if format, ok := extensions[strings.ToLower(filepath.Ext(filePath))]; ok {
+		return format
+	}
+	return FormatEnv
+}
+
+// ExtensionOf returns the file extension of the format.
+func ExtensionOf(format string) string {
+	if format == FormatEnv {
+		return ".env"
+	}
+	return "." + format
+}
+
+// CodecOf returns the codec of the file by its extension.
+func CodecOf(filePath string) Codec {
+	return codecs[FormatOf(filePath)]
+}
+
+// Formats returns the names of the formats, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeLines writes every item as a line rendered by the function.
+func encodeLines(w io.Writer, items []*Item, render func(item *Item) string) (int64, error) {
+	var written int64
+	for _, item := range items {
+		n, err := io.WriteString(w, render(item))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// envCodec reads and writes key-value files, the format of kvf itself.
+type envCodec struct{}
+
+type envDecoder struct {
+	*LineParser
+}
+
+func (d envDecoder) Close() ([]*Item, error) {
+	return nil, d.LineParser.Close()
+}
+
+func (envCodec) NewDecoder() Decoder {
+	return envDecoder{NewLineParser()}
+}
+
+func (envCodec) SetVal(item *Item, val string) error {
+	item.SetVal(val)
+	return nil
+}
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isEnvKey reports whether the key reads back as the same key from a key-value
+// file, i.e. it is neither cut at "=" or a blank nor read as a comment.
+func isEnvKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "#") && !strings.ContainsFunc(key, func(c rune) bool {
+		return c == '=' || unicode.IsSpace(c) || unicode.IsControl(c)
+	})
+}
+
+// Add adds the item to the end of the file. Keys of items read from other
+// formats, e.g. "server.host" of an INI file, have to be variable names, so
+// that shells and other readers of the file take them. Keys given by the user
+// only have to read back as the same key.
+func (envCodec) Add(items []*Item, item *Item) ([]*Item, error) {
+	if item.foreign && !envKeyPattern.MatchString(item.Key) || !isEnvKey(item.Key) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, item.Key)
+	}
+
+	added := &Item{
+		Key:     item.Key,
+		Val:     item.Val,
+		Quote:   quoteFor(item.Val, ""),
+		Export:  item.Export,
+		Comment: item.Comment,
+	}
+	return append(items, added), nil
+}
+
+func (envCodec) Encode(w io.Writer, items []*Item) (int64, error) {
+	return encodeLines(w, items, (*Item).ToLine)
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNotFlat      = errors.New("document is not a flat object")
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// documentDecoder collects the lines of formats whose files are parsed as a
+// whole, which happens when the decoder is closed.
+type documentDecoder struct {
+	lines  []string
+	decode func(data string) ([]*Item, error)
+}
+
+func (d *documentDecoder) Parse(line string) (*Item, error) {
+	d.lines = append(d.lines, line)
+	return nil, nil
+}
+
+func (d *documentDecoder) Close() ([]*Item, error) {
+	// Lines are read without their line breaks, the last one of which is
+	// significant e.g. for YAML block scalars.
+	data := strings.Join(d.lines, "\n") + "\n"
+	d.lines = nil
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+	return d.decode(data)
+}
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// isLiteral reports whether the value is a number or a boolean, the same in
+// JSON and YAML.
+func isLiteral(val string) bool {
+	return val == "true" || val == "false" || numberPattern.MatchString(val)
+}
+
+// setTypedVal changes the value of an item of a JSON or YAML file. Values
+// which are not strings, i.e. numbers, booleans and nulls, are unquoted and
+// stay so as long as the new value is of such a type, so that e.g. a counter
+// remains a number.
+func setTypedVal(item *Item, val string) {
+	if item.Quote == "" && !isLiteral(val) && !(val == "" && item.Val == "") {
+		item.Quote = doubleQuote
+		item.typed = false
+	}
+	item.Val = val
+}
+
+// addTyped adds an item to a JSON or YAML file, after the last key if there
+// is any, so that comments at the end of the file stay there. Its value is a
+// string, unless it is a value of another JSON or YAML file which is not.
+func addTyped(items []*Item, item *Item) ([]*Item, error) {
+	if item.Key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	pos := len(items)
+	for k, i := range items {
+		if i.IsKeyValue() {
+			pos = k + 1
+		}
+	}
+	items = append(items, nil)
+	copy(items[pos+1:], items[pos:])
+	added := &Item{Key: item.Key, Val: item.Val, Quote: doubleQuote, Comment: item.Comment, foreign: true}
+	if item.typed && (isLiteral(item.Val) || item.Val == "") {
+		added.Quote = ""
+		added.typed = true
+	}
+	items[pos] = added
+	return items, nil
+}
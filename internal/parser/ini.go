@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// iniCodec reads and writes INI files. The keys of a section are qualified
+// with its name, e.g. "key" of "[section]" is "section.key", while keys
+// before the first section are left as they are. Values may be put in double
+// quotes, which have no escape sequences, and ";" or "#" starts a comment.
+type iniCodec struct{}
+
+type iniDecoder struct {
+	section string
+	line    int
+}
+
+func (iniCodec) NewDecoder() Decoder {
+	return &iniDecoder{}
+}
+
+func (d *iniDecoder) Parse(line string) (*Item, error) {
+	d.line++
+	item, err := d.parse(line)
+	if item != nil {
+		item.Line = d.line
+		item.foreign = true
+	}
+	return item, err
+}
+
+func (d *iniDecoder) Close() ([]*Item, error) {
+	return nil, nil
+}
+
+func (d *iniDecoder) parse(line string) (*Item, error) {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "":
+		return &Item{IsEmpty: true, Raw: line}, nil
+	case trimmed[0] == ';' || trimmed[0] == '#':
+		return &Item{IsComment: true, Val: strings.TrimSpace(strings.TrimLeft(trimmed, ";#")), Raw: line}, nil
+	case trimmed[0] == '[':
+		end := strings.Index(trimmed, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLine, trimmed)
+		}
+		d.section = strings.TrimSpace(trimmed[1:end])
+		return &Item{IsSection: true, Key: d.section, Raw: line}, nil
+	}
+
+	sep := strings.IndexAny(line, "=:")
+	if sep < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, trimmed)
+	}
+
+	item := &Item{Key: qualify(d.section, strings.TrimSpace(line[:sep])), Raw: line}
+	rest := line[sep+1:]
+	item.valStart = sep + 1 + len(rest) - len(strings.TrimLeftFunc(rest, unicode.IsSpace))
+	val := line[item.valStart:]
+
+	if end := closingINIQuote(val); end > 0 {
+		item.Quote = doubleQuote
+		item.Val = val[1:end]
+		item.valEnd = item.valStart + end + 1
+		item.Comment = iniCommentText(val[end+1:])
+		return item, nil
+	}
+
+	end := inlineINIComment(val)
+	item.Val = strings.TrimRightFunc(val[:end], unicode.IsSpace)
+	item.valEnd = item.valStart + len(item.Val)
+	item.Comment = iniCommentText(val[end:])
+	return item, nil
+}
+
+func iniCommentText(comment string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(comment), ";#"))
+}
+
+func qualify(section string, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// closingINIQuote returns the position of the quote closing a double-quoted
+// value, which is the last quote before an optional comment, or -1 when the
+// value is not quoted.
+func closingINIQuote(val string) int {
+	if !strings.HasPrefix(val, doubleQuote) {
+		return -1
+	}
+	for end := strings.LastIndex(val, doubleQuote); end > 0; end = strings.LastIndex(val[:end], doubleQuote) {
+		after := strings.TrimSpace(val[end+1:])
+		if after == "" || after[0] == ';' || after[0] == '#' {
+			return end
+		}
+	}
+	return -1
+}
+
+// inlineINIComment returns the position of the comment in an unquoted value,
+// or the length of the value when there is none. A comment starts with ";" or
+// "#" preceded by whitespace.
+func inlineINIComment(val string) int {
+	for i := 1; i < len(val); i++ {
+		if (val[i] == ';' || val[i] == '#') && (val[i-1] == ' ' || val[i-1] == '\t') {
+			return i
+		}
+	}
+	return len(val)
+}
+
+// formatINIValue returns the value as it is written to a file, in double
+// quotes when it would not read back unchanged without them.
+func formatINIValue(val string, current string) string {
+	if current == doubleQuote || val != strings.TrimSpace(val) || strings.HasPrefix(val, doubleQuote) ||
+		inlineINIComment(val) < len(val) {
+		return doubleQuote + val + doubleQuote
+	}
+	return val
+}
+
+func checkINIValue(key string, val string) error {
+	if strings.ContainsAny(val, "\r\n") {
+		return fmt.Errorf("%w: INI values can't span several lines: %s", ErrUnsupportedValue, key)
+	}
+	return nil
+}
+
+func (iniCodec) SetVal(item *Item, val string) error {
+	err := checkINIValue(item.Key, val)
+	if err != nil {
+		return err
+	}
+	formatted := formatINIValue(val, item.Quote)
+	item.Val = val
+	if strings.HasPrefix(formatted, doubleQuote) {
+		item.Quote = doubleQuote
+	}
+	if item.Raw != "" {
+		item.Raw = item.Raw[:item.valStart] + formatted + item.Raw[item.valEnd:]
+		item.valEnd = item.valStart + len(formatted)
+	}
+	return nil
+}
+
+// Add adds the item to the section its key is qualified with. The longest
+// name of an existing section is taken when several of them qualify the key.
+// Otherwise a new section, named by the key up to its last dot, is added at
+// the end, and keys without a dot go before the first section.
+func (iniCodec) Add(items []*Item, item *Item) ([]*Item, error) {
+	if item.Key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	section := ""
+	for _, i := range items {
+		if i.IsSection && len(i.Key) > len(section) && strings.HasPrefix(item.Key, i.Key+".") {
+			section = i.Key
+		}
+	}
+	newSection := false
+	if section == "" {
+		if dot := strings.LastIndex(item.Key, "."); dot > 0 {
+			section = item.Key[:dot]
+			newSection = true
+		}
+	}
+
+	name := item.Key
+	if section != "" {
+		name = item.Key[len(section)+1:]
+	}
+	if name == "" || strings.ContainsAny(name, "=:\r\n") || strings.TrimSpace(name) != name ||
+		strings.ContainsAny(name[:1], "[;#") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, item.Key)
+	}
+	err := checkINIValue(item.Key, item.Val)
+	if err != nil {
+		return nil, err
+	}
+	formatted := formatINIValue(item.Val, "")
+
+	added := &Item{
+		Key:      item.Key,
+		Val:      item.Val,
+		Comment:  item.Comment,
+		Raw:      name + " = " + formatted,
+		valStart: len(name) + 3,
+		valEnd:   len(name) + 3 + len(formatted),
+		foreign:  true,
+	}
+	if item.Comment != "" {
+		added.Raw += " ; " + item.Comment
+	}
+	if strings.HasPrefix(formatted, doubleQuote) {
+		added.Quote = doubleQuote
+	}
+
+	if newSection {
+		if strings.ContainsAny(section, "]\r\n") {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidKey, item.Key)
+		}
+		if len(items) > 0 && !items[len(items)-1].IsEmpty {
+			items = append(items, &Item{IsEmpty: true, foreign: true})
+		}
+		header := &Item{IsSection: true, Key: section, Raw: "[" + section + "]", foreign: true}
+		return append(items, header, added), nil
+	}
+
+	// The item goes after the last key of its section. Keys before the first
+	// section go after its last line which is not empty.
+	pos, inSection := 0, section == ""
+	for k, i := range items {
+		if i.IsSection {
+			if inSection && section == "" {
+				break
+			}
+			inSection = i.Key == section
+			if inSection {
+				pos = k + 1
+			}
+			continue
+		}
+		if inSection && (i.IsKeyValue() || section == "" && !i.IsEmpty) {
+			pos = k + 1
+		}
+	}
+
+	items = append(items, nil)
+	copy(items[pos+1:], items[pos:])
+	items[pos] = added
+	return items, nil
+}
+
+func (iniCodec) Encode(w io.Writer, items []*Item) (int64, error) {
+	return encodeLines(w, items, func(item *Item) string {
+		switch {
+		case item.Raw != "" && item.foreign:
+			return item.Raw + "\n"
+		case item.IsEmpty:
+			return "\n"
+		case item.IsComment:
+			return "; " + item.Val + "\n"
+		case item.IsSection:
+			return "[" + item.Key + "]\n"
+		default:
+			return item.Key + " = " + formatINIValue(item.Val, item.Quote) + "\n"
+		}
+	})
+}
@@ -1,22 +1,54 @@
 package parser
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyKey = errors.New("key is empty")
+)
 
 type Item struct {
 	IsEmpty   bool
 	IsComment bool
+	// IsSection is set for the section headers of INI files. Key holds the
+	// name of the section.
+	IsSection bool
 	Key       string
 	Val       string
 	Quote     string
+	// Export is set for lines starting with the shell "export" keyword.
+	Export bool
+	// Comment is the text of the inline comment following the value.
+	Comment string
+
+	// Raw is the original text of a parsed line. Lines that were read from a
+	// file are written back from it, so their formatting is kept as is.
+	Raw string
+	// Line is the number of the line the item starts at, counted from 1. It is
+	// zero for items which were not parsed.
+	Line int
+	// valStart and valEnd delimit the value in Raw, including its quotes.
+	valStart int
+	valEnd   int
+	// foreign is set for items read from other formats than key-value files,
+	// whose Raw is not written in the syntax of key-value files.
+	foreign bool
+	// typed is set for the values of JSON and YAML files which are not
+	// strings.
+	typed bool
 }
 
 func NewItem(key string, val string) (*Item, error) {
 	if "" == key {
-		return nil, fmt.Errorf("key is empty")
+		return nil, ErrEmptyKey
 	}
 	return &Item{
-		Key: key,
-		Val: val,
+		Key:   key,
+		Val:   val,
+		Quote: quoteFor(val, ""),
 	}, nil
 }
 
@@ -34,12 +66,60 @@ func (ic *ItemCollection) Add(item *Item) {
 	*ic.Items = append(*ic.Items, item)
 }
 
+// IsKeyValue reports whether the item holds a key with its value, as opposed to
+// empty lines, comments and section headers.
+func (i *Item) IsKeyValue() bool {
+	return !i.IsEmpty && !i.IsComment && !i.IsSection
+}
+
+// SetVal changes the value of the item. For parsed items only the value part
+// of the original line is replaced.
+func (i *Item) SetVal(val string) {
+	i.Val = val
+	i.Quote = quoteFor(val, i.Quote)
+	if i.Raw == "" {
+		return
+	}
+
+	formatted := quote(val, i.Quote)
+	i.Raw = i.Raw[:i.valStart] + formatted + i.Raw[i.valEnd:]
+	i.valEnd = i.valStart + len(formatted)
+}
+
+// RawVal returns the value as it is written in the file, without its quotes
+// and with escape sequences left undecoded. Values of other formats are taken
+// as if they were written in a key-value file.
+func (i *Item) RawVal() string {
+	if i.foreign {
+		if i.Quote == doubleQuote {
+			return strings.ReplaceAll(i.Val, `\`, `\\`)
+		}
+		return i.Val
+	}
+
+	raw := quote(i.Val, i.Quote)
+	if i.Raw != "" {
+		raw = i.Raw[i.valStart:i.valEnd]
+	}
+	return raw[len(i.Quote) : len(raw)-len(i.Quote)]
+}
+
 func (i *Item) ToLine() string {
+	if i.Raw != "" {
+		return i.Raw + "\n"
+	}
 	if i.IsEmpty {
 		return "\n"
 	}
 	if i.IsComment {
 		return fmt.Sprintf("# %s\n", i.Val)
 	}
-	return fmt.Sprintf("%s=%s%s%s\n", i.Key, i.Quote, i.Val, i.Quote)
+	line := i.Key + "=" + quote(i.Val, i.Quote)
+	if i.Export {
+		line = "export " + line
+	}
+	if i.Comment != "" {
+		line += " # " + i.Comment
+	}
+	return line + "\n"
 }
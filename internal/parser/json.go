@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonCodec reads and writes flat JSON objects, whose values are strings,
+// numbers, booleans or nulls. Values which are not strings have no quotes
+// and nulls read as empty values. Files are written indented by two spaces,
+// without comments and empty lines, which JSON does not have.
+type jsonCodec struct{}
+
+func (jsonCodec) NewDecoder() Decoder {
+	return &documentDecoder{decode: decodeJSON}
+}
+
+func decodeJSON(data string) ([]*Item, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, ErrNotFlat
+	}
+
+	var items []*Item
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		// Readers disagree on which of the values of a duplicate key wins.
+		if seen[tok.(string)] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKey, tok)
+		}
+		seen[tok.(string)] = true
+		item := &Item{
+			Key:     tok.(string),
+			Line:    strings.Count(data[:dec.InputOffset()], "\n") + 1,
+			foreign: true,
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case string:
+			item.Val = t
+			item.Quote = doubleQuote
+		case json.Number:
+			item.Val = t.String()
+			item.typed = true
+		case bool:
+			item.Val = fmt.Sprint(t)
+			item.typed = true
+		case nil:
+			item.typed = true
+		default:
+			return nil, fmt.Errorf("%w: %s has a nested value", ErrNotFlat, item.Key)
+		}
+		items = append(items, item)
+	}
+
+	_, err = dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	_, err = dec.Token()
+	if err != io.EOF {
+		return nil, fmt.Errorf("%w: unexpected data after the JSON object", ErrInvalidLine)
+	}
+	return items, nil
+}
+
+func (jsonCodec) SetVal(item *Item, val string) error {
+	setTypedVal(item, val)
+	return nil
+}
+
+func (jsonCodec) Add(items []*Item, item *Item) ([]*Item, error) {
+	return addTyped(items, item)
+}
+
+func (jsonCodec) Encode(w io.Writer, items []*Item) (int64, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	first := true
+	for _, item := range items {
+		if !item.IsKeyValue() {
+			continue
+		}
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+
+		b.WriteString("\n  " + jsonString(item.Key) + ": ")
+		switch {
+		case item.Quote != "":
+			b.WriteString(jsonString(item.Val))
+		case item.Val == "":
+			b.WriteString("null")
+		default:
+			b.WriteString(item.Val)
+		}
+	}
+	if !first {
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// jsonString returns the string as JSON. HTML characters are kept as they are
+// for readability.
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -1,45 +1,188 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"unicode"
 )
 
+var (
+	ErrInvalidLine       = errors.New("invalid line")
+	ErrUnterminatedQuote = errors.New("unterminated quoted value")
+)
+
+// LineParser parses key-value files line by line. Double-quoted values may
+// span several lines, so the parser keeps the lines of an unterminated value
+// until its closing quote is found.
 type LineParser struct {
+	pending []string
+	// line is the number of the last parsed line, start the one the current
+	// item started at.
+	line  int
+	start int
 }
 
 func NewLineParser() *LineParser {
 	return &LineParser{}
 }
 
-func (*LineParser) Parse(line string) (*Item, error) {
-	line = strings.TrimSpace(line)
+// Parse parses a single line. It returns a nil item without an error when the
+// line opens or continues a multi-line value and more lines are needed.
+func (p *LineParser) Parse(line string) (*Item, error) {
+	p.line++
+	if p.pending == nil {
+		p.start = p.line
+	}
 
-	item := &Item{}
+	item, err := p.parse(line)
+	if item != nil {
+		item.Line = p.start
+	}
+	return item, err
+}
 
-	if line == "" {
-		return &Item{IsEmpty: true}, nil
-	} else if strings.HasPrefix(line, "#") {
-		item.IsComment = true
-		item.Val = strings.TrimSpace(strings.TrimLeft(line, "#"))
-	} else {
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid line: %s", line)
+func (p *LineParser) parse(line string) (*Item, error) {
+	if p.pending != nil {
+		p.pending = append(p.pending, line)
+		raw := strings.Join(p.pending, "\n")
+		if isUnterminated(raw) {
+			return nil, nil
 		}
-		item.Key = strings.TrimSpace(parts[0])
-		item.Val = strings.TrimSpace(parts[1])
+		p.pending = nil
+		return parseKeyValue(raw)
+	}
+
+	trimmed := strings.TrimSpace(line)
+
+	item := &Item{Raw: line}
+
+	if trimmed == "" {
+		item.IsEmpty = true
+		return item, nil
+	} else if strings.HasPrefix(trimmed, "#") {
+		item.IsComment = true
+		item.Val = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		return item, nil
+	}
+
+	if !strings.Contains(line, "=") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, trimmed)
+	}
+
+	if isUnterminated(line) {
+		p.pending = []string{line}
+		return nil, nil
+	}
+
+	return parseKeyValue(line)
+}
+
+// Close finishes parsing. It reports an error when the last value was left
+// unterminated.
+func (p *LineParser) Close() error {
+	if p.pending == nil {
+		return nil
+	}
+	first := strings.TrimSpace(p.pending[0])
+	p.pending = nil
+	return fmt.Errorf("%w: %s", ErrUnterminatedQuote, first)
+}
 
-		if strings.HasPrefix(item.Val, "\"") && strings.HasSuffix(item.Val, "\"") {
-			item.Quote = "\""
-		} else if strings.HasPrefix(item.Val, "'") && strings.HasSuffix(item.Val, "'") {
-			item.Quote = "'"
+// isUnterminated reports whether the raw key-value text has a double-quoted
+// value without its closing quote.
+func isUnterminated(raw string) bool {
+	val := strings.TrimLeftFunc(raw[strings.Index(raw, "=")+1:], unicode.IsSpace)
+	return strings.HasPrefix(val, doubleQuote) && closingQuote(val[1:]) < 0
+}
+
+func parseKeyValue(raw string) (*Item, error) {
+	item := &Item{Raw: raw}
+
+	eq := strings.Index(raw, "=")
+	item.Key = strings.TrimSpace(raw[:eq])
+	if key, ok := cutExport(item.Key); ok {
+		item.Key = key
+		item.Export = true
+	}
+
+	rest := raw[eq+1:]
+	item.valStart = eq + 1 + len(rest) - len(strings.TrimLeftFunc(rest, unicode.IsSpace))
+	item.valEnd = eq + 1 + len(strings.TrimRightFunc(rest, unicode.IsSpace))
+	if item.valStart > item.valEnd {
+		item.valStart = item.valEnd
+	}
+	val := raw[item.valStart:item.valEnd]
+
+	var end int
+	switch {
+	case strings.HasPrefix(val, doubleQuote):
+		end = closingQuote(val[1:]) + 2
+		item.Quote = doubleQuote
+	case strings.HasPrefix(val, singleQuote) && strings.Contains(val[1:], singleQuote):
+		end = strings.Index(val[1:], singleQuote) + 2
+		item.Quote = singleQuote
+	case strings.HasPrefix(val, "#") && item.valStart > eq+1:
+		// An empty value followed by an inline comment.
+		item.valStart = eq + 1
+		item.valEnd = item.valStart
+		item.Comment = commentText(val)
+		return item, nil
+	default:
+		end = inlineComment(val)
+		item.valEnd = item.valStart + len(strings.TrimRightFunc(val[:end], unicode.IsSpace))
+		item.Val = raw[item.valStart:item.valEnd]
+		item.Comment = commentText(val[end:])
+		return item, nil
+	}
+
+	if end <= 1 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, strings.TrimSpace(raw))
+	}
+
+	after := strings.TrimSpace(val[end:])
+	if after != "" && !strings.HasPrefix(after, "#") {
+		// Text after the closing quote. Values which still end with the quote
+		// are read as a whole, the same way they always were.
+		if len(val) < 2 || !strings.HasSuffix(val, item.Quote) || strings.Contains(val, "\n") {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLine, strings.TrimSpace(raw))
 		}
+		end = len(val)
+		after = ""
 	}
 
-	if item.Quote != "" {
-		item.Val = item.Val[1 : len(item.Val)-1]
+	item.valEnd = item.valStart + end
+	item.Comment = commentText(after)
+	if item.Quote == doubleQuote {
+		item.Val = Unescape(val[1 : end-1])
+	} else {
+		item.Val = val[1 : end-1]
 	}
 
 	return item, nil
 }
+
+// cutExport removes the shell "export" keyword from the beginning of the key.
+func cutExport(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, "export")
+	if !ok || rest == "" || !unicode.IsSpace(rune(rest[0])) {
+		return key, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// inlineComment returns the position of the comment in an unquoted value, or
+// the length of the value when there is none. A comment starts with a "#"
+// which is preceded by whitespace.
+func inlineComment(val string) int {
+	for i := 1; i < len(val); i++ {
+		if val[i] == '#' && (val[i-1] == ' ' || val[i-1] == '\t') {
+			return i
+		}
+	}
+	return len(val)
+}
+
+func commentText(comment string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+}
@@ -0,0 +1,265 @@
+package parser
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// propertiesCodec reads and writes Java .properties files. Keys are separated
+// from their values by "=", ":" or whitespace, a backslash at the end of a
+// line continues the value on the next line and "#" or "!" starts a comment.
+// Files are read and written as UTF-8.
+type propertiesCodec struct{}
+
+type propertiesDecoder struct {
+	pending []string
+	line    int
+	start   int
+}
+
+func (propertiesCodec) NewDecoder() Decoder {
+	return &propertiesDecoder{}
+}
+
+func (d *propertiesDecoder) Parse(line string) (*Item, error) {
+	d.line++
+	if d.pending == nil {
+		trimmed := strings.TrimLeft(line, " \t\f")
+		switch {
+		case trimmed == "":
+			return &Item{IsEmpty: true, Raw: line, Line: d.line, foreign: true}, nil
+		case trimmed[0] == '#' || trimmed[0] == '!':
+			val := strings.TrimSpace(strings.TrimLeft(trimmed, "#!"))
+			return &Item{IsComment: true, Val: val, Raw: line, Line: d.line, foreign: true}, nil
+		}
+		d.start = d.line
+	}
+
+	d.pending = append(d.pending, line)
+	if isContinued(line) {
+		return nil, nil
+	}
+
+	item := parseProperty(strings.Join(d.pending, "\n"))
+	item.Line = d.start
+	d.pending = nil
+	return item, nil
+}
+
+func (d *propertiesDecoder) Close() ([]*Item, error) {
+	if d.pending == nil {
+		return nil, nil
+	}
+	// The last line ends with a backslash, continuing the value at the end
+	// of the file.
+	item := parseProperty(strings.Join(d.pending, "\n"))
+	item.Line = d.start
+	d.pending = nil
+	return []*Item{item}, nil
+}
+
+// isContinued reports whether the line ends with an odd number of
+// backslashes, the last of which continues the line.
+func isContinued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// skipContinuation returns the position after the line continuation at the
+// position, or the position itself when there is none.
+func skipContinuation(raw string, i int) int {
+	if i+1 < len(raw) && raw[i] == '\\' && raw[i+1] == '\n' {
+		i += 2
+		for i < len(raw) && isPropertiesSpace(raw[i]) {
+			i++
+		}
+	}
+	return i
+}
+
+func isPropertiesSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+// parseProperty parses the lines of a key with its value.
+func parseProperty(raw string) *Item {
+	i := 0
+	for i < len(raw) && isPropertiesSpace(raw[i]) {
+		i++
+	}
+	keyStart := i
+	for i < len(raw) {
+		if next := skipContinuation(raw, i); next != i {
+			i = next
+			continue
+		}
+		c := raw[i]
+		if c == '=' || c == ':' || isPropertiesSpace(c) {
+			break
+		}
+		if c == '\\' {
+			i++
+		}
+		i++
+	}
+	// A backslash ending the key escapes nothing.
+	i = min(i, len(raw))
+	keyEnd := i
+
+	skipSpace := func() {
+		for i < len(raw) {
+			if next := skipContinuation(raw, i); next != i {
+				i = next
+			} else if isPropertiesSpace(raw[i]) {
+				i++
+			} else {
+				break
+			}
+		}
+	}
+	skipSpace()
+	if i < len(raw) && (raw[i] == '=' || raw[i] == ':') {
+		i++
+		skipSpace()
+	}
+
+	return &Item{
+		Key:      unescapeProperties(raw[keyStart:keyEnd]),
+		Val:      unescapeProperties(raw[i:]),
+		Raw:      raw,
+		valStart: i,
+		valEnd:   len(raw),
+		foreign:  true,
+	}
+}
+
+// unescapeProperties decodes the escape sequences and line continuations of
+// a key or a value.
+func unescapeProperties(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	var units []uint16
+	flush := func() {
+		if units != nil {
+			b.WriteString(string(utf16.Decode(units)))
+			units = nil
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		if next := skipContinuation(s, i); next != i {
+			i = next - 1
+			continue
+		}
+		if s[i] != '\\' || i == len(s)-1 {
+			flush()
+			if s[i] != '\\' {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+
+		i++
+		if s[i] == 'u' && i+4 < len(s) {
+			if u, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+				// Characters outside of the basic plane are written as
+				// surrogate pairs, decoded together.
+				units = append(units, uint16(u))
+				i += 4
+				continue
+			}
+		}
+		flush()
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// escapeProperties escapes the text, so that it reads back unchanged as a key
+// or as a value.
+func escapeProperties(s string, isKey bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\f':
+			b.WriteString(`\f`)
+		case c == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case (c == '=' || c == ':') && (isKey || i == 0):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case (c == '#' || c == '!') && isKey && i == 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (propertiesCodec) SetVal(item *Item, val string) error {
+	item.Val = val
+	if item.Raw != "" {
+		item.Raw = item.Raw[:item.valStart] + escapeProperties(val, false)
+		item.valEnd = len(item.Raw)
+	}
+	return nil
+}
+
+func (propertiesCodec) Add(items []*Item, item *Item) ([]*Item, error) {
+	if item.Key == "" {
+		return nil, ErrEmptyKey
+	}
+	key := escapeProperties(item.Key, true)
+	raw := key + "=" + escapeProperties(item.Val, false)
+	return append(items, &Item{
+		Key:      item.Key,
+		Val:      item.Val,
+		Raw:      raw,
+		valStart: len(key) + 1,
+		valEnd:   len(raw),
+		foreign:  true,
+	}), nil
+}
+
+func (propertiesCodec) Encode(w io.Writer, items []*Item) (int64, error) {
+	return encodeLines(w, items, func(item *Item) string {
+		switch {
+		case item.Raw != "" && item.foreign:
+			return item.Raw + "\n"
+		case item.IsEmpty:
+			return "\n"
+		case item.IsComment:
+			return "# " + item.Val + "\n"
+		default:
+			return escapeProperties(item.Key, true) + "=" + escapeProperties(item.Val, false) + "\n"
+		}
+	})
+}
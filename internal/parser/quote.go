@@ -0,0 +1,124 @@
+package parser
+
+import "strings"
+
+const (
+	doubleQuote = "\""
+	singleQuote = "'"
+)
+
+var doubleQuoteEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\r", "\\r",
+)
+
+// quoteFor returns the quote to write the value with. The current quote is
+// kept unless the value cannot be represented with it. Unquoted values that
+// would not read back unchanged get single quotes, or double quotes when they
+// contain characters single quotes cannot hold.
+func quoteFor(val string, current string) string {
+	switch {
+	case current == doubleQuote:
+		return doubleQuote
+	case current == singleQuote && fitsSingleQuotes(val):
+		return singleQuote
+	case current == "" && fitsUnquoted(val):
+		return ""
+	case fitsSingleQuotes(val):
+		return singleQuote
+	default:
+		return doubleQuote
+	}
+}
+
+func fitsUnquoted(val string) bool {
+	if val != strings.TrimSpace(val) {
+		return false
+	}
+	if strings.HasPrefix(val, doubleQuote) || strings.HasPrefix(val, singleQuote) {
+		return false
+	}
+	return !strings.ContainsAny(val, "#$\n\r")
+}
+
+func fitsSingleQuotes(val string) bool {
+	return !strings.ContainsAny(val, "'\n\r")
+}
+
+// quote returns the value as it is written to a file.
+func quote(val string, q string) string {
+	if q == doubleQuote {
+		return q + escapeReferences(doubleQuoteEscaper.Replace(val)) + q
+	}
+	return q + val + q
+}
+
+// escapeReferences escapes the "$" characters which would start a reference
+// when the value is expanded. Any other "$" is written as it is, so values
+// without references read the same with or without expansion.
+func escapeReferences(val string) string {
+	if !strings.Contains(val, "$") {
+		return val
+	}
+
+	var b strings.Builder
+	b.Grow(len(val) + 1)
+	for i := 0; i < len(val); i++ {
+		if val[i] == '$' && i+1 < len(val) && startsReference(val[i+1]) {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(val[i])
+	}
+	return b.String()
+}
+
+func startsReference(c byte) bool {
+	return c == '{' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Unescape decodes the escape sequences of a double-quoted value. Unknown
+// sequences are kept as they are.
+func Unescape(val string) string {
+	if !strings.Contains(val, "\\") {
+		return val
+	}
+
+	var b strings.Builder
+	b.Grow(len(val))
+	for i := 0; i < len(val); i++ {
+		if val[i] != '\\' || i == len(val)-1 {
+			b.WriteByte(val[i])
+			continue
+		}
+		i++
+		switch val[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\', '$':
+			b.WriteByte(val[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(val[i])
+		}
+	}
+	return b.String()
+}
+
+// closingQuote returns the index of the first unescaped double quote in s, or
+// -1 if there is none.
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+// yamlCodec reads and writes flat YAML mappings, whose values are scalars.
+// Like in JSON files, scalars which are not strings have no quotes and nulls
+// read as empty values. Comments are kept, while the file is written in the
+// formatting of the YAML encoder.
+type yamlCodec struct{}
+
+func (yamlCodec) NewDecoder() Decoder {
+	return &documentDecoder{decode: decodeYAML}
+}
+
+func decodeYAML(data string) ([]*Item, error) {
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	var doc yaml.Node
+	err := dec.Decode(&doc)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode {
+		return nil, nil
+	}
+	var next yaml.Node
+	err = dec.Decode(&next)
+	if err == nil {
+		return nil, fmt.Errorf("%w: the file has several documents", ErrNotFlat)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	items := yamlComments(nil, doc.HeadComment)
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, ErrNotFlat
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		for val.Kind == yaml.AliasNode {
+			val = val.Alias
+		}
+		if key.Tag == "!!merge" || val.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%w: %s has a nested value", ErrNotFlat, key.Value)
+		}
+
+		items = yamlComments(items, key.HeadComment)
+		item := &Item{Key: key.Value, Line: key.Line, foreign: true}
+		switch val.Tag {
+		case "!!str":
+			item.Val = val.Value
+			item.Quote = doubleQuote
+		case "!!null":
+			item.typed = true
+		default:
+			item.Val = val.Value
+			item.typed = true
+		}
+		item.Comment = yamlCommentText(val.LineComment + key.LineComment)
+		items = append(items, item)
+		items = yamlComments(items, key.FootComment)
+	}
+
+	items = yamlComments(items, root.FootComment)
+	return yamlComments(items, doc.FootComment), nil
+}
+
+// yamlComments adds the lines of the comment as comment items.
+func yamlComments(items []*Item, comment string) []*Item {
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.TrimSpace(line) != "" {
+			items = append(items, &Item{IsComment: true, Val: yamlCommentText(line), foreign: true})
+		}
+	}
+	return items
+}
+
+func yamlCommentText(comment string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(comment), "#"))
+}
+
+func (yamlCodec) SetVal(item *Item, val string) error {
+	setTypedVal(item, val)
+	return nil
+}
+
+func (yamlCodec) Add(items []*Item, item *Item) ([]*Item, error) {
+	return addTyped(items, item)
+}
+
+func (yamlCodec) Encode(w io.Writer, items []*Item) (int64, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	var comments []string
+	for _, item := range items {
+		switch {
+		case item.IsComment:
+			comments = append(comments, "# "+item.Val)
+			continue
+		case !item.IsKeyValue():
+			continue
+		}
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item.Key}
+		key.HeadComment = strings.Join(comments, "\n")
+		comments = nil
+
+		val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item.Val}
+		switch {
+		case item.Quote != "":
+		case item.Val == "":
+			val.Tag, val.Value = "!!null", "null"
+		default:
+			val.Tag = ""
+		}
+		if item.Comment != "" {
+			val.LineComment = "# " + item.Comment
+		}
+		root.Content = append(root.Content, key, val)
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	doc.FootComment = strings.Join(comments, "\n")
+
+	cw := &countingWriter{w: w}
+	enc := yaml.NewEncoder(cw)
+	enc.SetIndent(2)
+	err := enc.Encode(doc)
+	if err == nil {
+		err = enc.Close()
+	}
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}